@@ -49,6 +49,9 @@ func main() {
 		},
 	}
 	rootCmd.AddCommand(cmd.NewCmdCreate())
+	rootCmd.AddCommand(cmd.NewCmdUpdate())
+	rootCmd.AddCommand(cmd.NewCmdVerify())
+	rootCmd.AddCommand(cmd.NewCmdPackage())
 	rootCmd.AddCommand(v.NewCmdVersion())
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal(err)
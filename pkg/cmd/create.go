@@ -8,49 +8,126 @@ import (
 
 	"github.com/spf13/cobra"
 	"go.damarseta.id/chartify/pkg"
+	"helm.sh/helm/v3/pkg/chart"
 )
 
 func NewCmdCreate() *cobra.Command {
+	return newCmdGenerate("create NAME", "Create Helm Charts from Kubernetes api objects", pkg.Generator.Create)
+}
+
+// newCmdGenerate returns a command that reads Kubernetes objects as its flags
+// tell and passes the Generator for them to run.
+func newCmdGenerate(use, short string, run func(pkg.Generator) (string, error)) *cobra.Command {
 	var (
-		kubeDir      string
-		chartDir     string
-		preserveName bool
+		kubeDirs        []string
+		filenames       []string
+		chartDir        string
+		preserveName    bool
+		continueOnError bool
+		filter          pkg.FileFilter
+		chartfile       chart.Metadata
+		maintainers     []string
+		appVersionImage bool
+		lint            bool
+		verify          bool
+		packageChart    bool
+		packageDir      string
+		index           bool
+		indexURL        string
+		dryRun          bool
+		trackChanges    bool
 	)
 	ko := pkg.KubeObjects{}
 
 	cmd := &cobra.Command{
-		Use:   "create NAME",
-		Short: "Create Helm Charts from Kubernetes api objects",
+		Use:   use,
+		Short: short,
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
 				fmt.Println("ERROR : Provide a ChartName")
 				os.Exit(1)
 			}
+			for _, m := range maintainers {
+				maintainer, err := pkg.ParseMaintainer(m)
+				if err != nil {
+					log.Fatal(err)
+				}
+				chartfile.Maintainers = append(chartfile.Maintainers, maintainer)
+			}
 			gen := pkg.Generator{
-				Location:  checkLocation(chartDir),
-				ChartName: args[0],
+				ChartName:           args[0],
+				ContinueOnError:     continueOnError,
+				Chart:               chartfile,
+				AppVersionFromImage: appVersionImage,
+				Lint:                lint,
+				Verify:              verify,
+				Index:               index,
+				IndexURL:            indexURL,
+				TrackChanges:        trackChanges,
 			}
-			pkg.PreserveName = preserveName
-			if len(kubeDir) != 0 {
-				gen.YamlFiles = pkg.ReadLocalFiles(kubeDir)
+			if packageChart {
+				gen.PackageDir = packageDir
+			}
+			if dryRun {
+				gen.Output = pkg.StreamWriter{W: os.Stdout}
+				pkg.LogOutput = os.Stderr
 			} else {
-				ok := ko.CheckFlags()
-				if !ok {
-					fmt.Println("No object given.")
-					os.Exit(1)
+				gen.Location = checkLocation(chartDir)
+			}
+			pkg.PreserveName = preserveName
+			var err error
+			gen.YamlFiles, gen.YamlSources, err = pkg.ReadFiles(append(kubeDirs, filenames...), filter, os.Stdin)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if ko.CheckFlags() {
+				objects, err := ko.Extract()
+				if err != nil {
+					log.Fatal(err)
 				}
-				gen.YamlFiles = ko.Extract()
+				for _, obj := range objects {
+					gen.YamlFiles = append(gen.YamlFiles, obj)
+					gen.YamlSources = append(gen.YamlSources, "cluster")
+				}
+			}
+			if len(gen.YamlFiles) == 0 {
+				fmt.Println("No object given.")
+				os.Exit(1)
+			}
+			if _, err := run(gen); err != nil {
+				log.Fatal(err)
 			}
-			gen.Create()
 		},
 	}
-	cmd.Flags().StringVar(&kubeDir, "kube-dir", "", "Specify the directory of the yaml files for Kubernetes objects")
+	cmd.Flags().StringSliceVar(&kubeDirs, "kube-dir", kubeDirs, "Specify the directories of the yaml/json files for Kubernetes objects, read recursively")
+	cmd.Flags().StringSliceVarP(&filenames, "filename", "f", filenames, "Specify files or directories of Kubernetes objects to include in chart, or - to read them from stdin")
+	cmd.Flags().StringSliceVar(&filter.Include, "include", filter.Include, "Only read files in directories matching these glob patterns (matched against the file name and the relative path)")
+	cmd.Flags().StringSliceVar(&filter.Exclude, "exclude", filter.Exclude, "Skip files and directories matching these glob patterns when reading directories")
 	cmd.Flags().StringVar(&chartDir, "chart-dir", "charts", "Specify the location where charts will be created")
 	cmd.Flags().BoolVar(&preserveName, "preserve-name", false, "Specify if you want to preserve resources name from input yaml true/false (default: false)")
+	cmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "Skip objects that can not be converted instead of aborting, and report them at the end")
+	cmd.Flags().BoolVar(&trackChanges, "track-changes", false, "Keep a copy of the generated files in the .chartify directory of the chart, for chartify update to merge edits against (always on for update)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the files of the chart to stdout instead of writing them")
+	cmd.Flags().BoolVar(&lint, "lint", false, "Run Helm's chart linter on the created chart and fail on errors")
+	cmd.Flags().BoolVar(&verify, "verify", false, "Render the created chart and report the fields of the objects it does not reproduce")
+	cmd.Flags().BoolVar(&packageChart, "package", false, "Archive the created chart as <name>-<version>.tgz")
+	cmd.Flags().StringVar(&packageDir, "package-dir", ".", "Specify the directory where the chart archive is written")
+	cmd.Flags().BoolVar(&index, "index", false, "Regenerate the index.yaml of the package directory after archiving the chart")
+	cmd.Flags().StringVar(&indexURL, "index-url", "", "Specify the URL of the chart repository the index lists the charts under")
+	cmd.Flags().StringVar(&chartfile.Version, "version", "", "Specify the chart version (default \"0.1.0\")")
+	cmd.Flags().StringVar(&chartfile.AppVersion, "app-version", "", "Specify the version of the app in the chart")
+	cmd.Flags().BoolVar(&appVersionImage, "app-version-from-image", false, "Use the image tag of the first container as app version, unless --app-version is given")
+	cmd.Flags().StringVar(&chartfile.Description, "description", "", "Specify the chart description")
+	cmd.Flags().StringVar(&chartfile.Type, "type", "application", "Specify the chart type, application or library")
+	cmd.Flags().StringSliceVar(&chartfile.Keywords, "keywords", chartfile.Keywords, "Specify the chart keywords")
+	cmd.Flags().StringSliceVar(&maintainers, "maintainers", maintainers, "Specify the chart maintainers as name or \"name <email>\"")
+	cmd.Flags().StringVar(&chartfile.Home, "home", "", "Specify the URL of the project home page")
+	cmd.Flags().StringSliceVar(&chartfile.Sources, "sources", chartfile.Sources, "Specify the URLs of the project source code")
 	cmd.Flags().StringSliceVar(&ko.ConfigMaps, "configmaps", ko.ConfigMaps, "Specify the names of configmaps(configmap@namespace) to include in chart")
 	cmd.Flags().StringSliceVar(&ko.Daemons, "daemons", ko.Daemons, "Specify the names of daemons(daemon@namespace) to include in chart")
 	cmd.Flags().StringSliceVar(&ko.Deployments, "deployments", ko.Deployments, "Specify the names of deployments(deployments@namespace) to include in chart")
 	cmd.Flags().StringSliceVar(&ko.Jobs, "jobs", ko.Jobs, "Specify the names of jobs(job@namespace) to include in chart")
+	cmd.Flags().StringSliceVar(&ko.CronJobs, "cronjobs", ko.CronJobs, "Specify the names of cronjobs(cronjob@namespace) to include in chart")
 	cmd.Flags().StringSliceVar(&ko.PersistentVolumes, "pvs", ko.PersistentVolumes, "Specify the names of persistent volumes(pv@namespace) to include in chart")
 	cmd.Flags().StringSliceVar(&ko.PersistentVolumeClaims, "pvcs", ko.PersistentVolumeClaims, "Specify the names of persistent volume claims(pvc@namespace) to include in chart")
 	cmd.Flags().StringSliceVar(&ko.Pods, "pods", ko.Pods, "Specify the names of pods(pod@namespace) to include in chart")
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.damarseta.id/chartify/pkg"
+)
+
+func NewCmdPackage() *cobra.Command {
+	var (
+		destination string
+		index       bool
+		indexURL    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "package CHART",
+		Short: "Archive a chart as <name>-<version>.tgz",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println("ERROR : Provide a chart directory")
+				os.Exit(1)
+			}
+			if _, err := pkg.PackageChart(args[0], destination); err != nil {
+				log.Fatal(err)
+			}
+			if index {
+				if err := pkg.IndexRepository(destination, indexURL); err != nil {
+					log.Fatal(err)
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&destination, "destination", "d", ".", "Specify the directory where the chart archive is written")
+	cmd.Flags().BoolVar(&index, "index", false, "Regenerate the index.yaml of the destination directory after archiving the chart")
+	cmd.Flags().StringVar(&indexURL, "index-url", "", "Specify the URL of the chart repository the index lists the charts under")
+
+	return cmd
+}
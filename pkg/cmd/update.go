@@ -0,0 +1,10 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"go.damarseta.id/chartify/pkg"
+)
+
+func NewCmdUpdate() *cobra.Command {
+	return newCmdGenerate("update NAME", "Regenerate a chart from Kubernetes api objects, keeping the edits made to it", pkg.Generator.Update)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.damarseta.id/chartify/pkg"
+)
+
+func NewCmdVerify() *cobra.Command {
+	var (
+		kubeDirs  []string
+		filenames []string
+		filter    pkg.FileFilter
+	)
+
+	cmd := &cobra.Command{
+		Use:   "verify CHART",
+		Short: "Check that a chart renders the Kubernetes api objects it was created from",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				fmt.Println("ERROR : Provide a chart directory")
+				os.Exit(1)
+			}
+			objects, _, err := pkg.ReadFiles(append(kubeDirs, filenames...), filter, os.Stdin)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if len(objects) == 0 {
+				fmt.Println("No object given.")
+				os.Exit(1)
+			}
+			if err := pkg.VerifyChart(args[0], objects); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Println("VERIFY : SUCCESSFUL")
+		},
+	}
+	cmd.Flags().StringSliceVar(&kubeDirs, "kube-dir", kubeDirs, "Specify the directories of the yaml/json files the chart was created from, read recursively")
+	cmd.Flags().StringSliceVarP(&filenames, "filename", "f", filenames, "Specify files or directories of Kubernetes objects the chart was created from, or - to read them from stdin")
+	cmd.Flags().StringSliceVar(&filter.Include, "include", filter.Include, "Only read files in directories matching these glob patterns (matched against the file name and the relative path)")
+	cmd.Flags().StringSliceVar(&filter.Exclude, "exclude", filter.Exclude, "Skip files and directories matching these glob patterns when reading directories")
+
+	return cmd
+}
@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"fmt"
+
+	"github.com/appscode/go/encoding/yaml"
+	ylib "github.com/ghodss/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// CRDKind is the kind of CustomResourceDefinition objects. Helm 3 installs
+// them from the chart's crds/ directory, which is never templated, so they
+// are copied there instead of being turned into templates.
+const CRDKind = "CustomResourceDefinition"
+
+// crdTemplate returns a CustomResourceDefinition as it is written to the crds
+// directory of the chart, with status and server managed fields removed.
+func crdTemplate(kubeJson []byte) (string, valueFileGenerator, error) {
+	crd := &unstructured.Unstructured{}
+	if err := crd.UnmarshalJSON(kubeJson); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	cleanUpCRD(crd)
+	data, err := ylib.Marshal(crd.Object)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	return string(data), valueFileGenerator{}, nil
+}
+
+func cleanUpCRD(crd *unstructured.Unstructured) {
+	unstructured.RemoveNestedField(crd.Object, "status")
+	for _, f := range []string{"creationTimestamp", "generation", "managedFields", "resourceVersion", "selfLink", "uid"} {
+		unstructured.RemoveNestedField(crd.Object, "metadata", f)
+	}
+	annotations := crd.GetAnnotations()
+	delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(crd.Object, "metadata", "annotations")
+	} else {
+		crd.SetAnnotations(annotations)
+	}
+}
+
+// crdDependents lists the custom resources among objects whose definition
+// is one of the CustomResourceDefinitions among objects.
+func crdDependents(objects []string) []string {
+	crds := make(map[schema.GroupKind]string)
+	var resources []*unstructured.Unstructured
+	for _, v := range objects {
+		obj, err := parseUnstructured(v)
+		if err != nil {
+			continue // reported when the object itself is generated
+		}
+		if obj.GetKind() != CRDKind {
+			resources = append(resources, obj)
+			continue
+		}
+		group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+		crds[schema.GroupKind{Group: group, Kind: kind}] = obj.GetName()
+	}
+	var deps []string
+	for _, obj := range resources {
+		gk := obj.GroupVersionKind().GroupKind()
+		if crd, found := crds[gk]; found {
+			deps = append(deps, fmt.Sprintf("%s %q depends on %s %q", gk.Kind, obj.GetName(), CRDKind, crd))
+		}
+	}
+	return deps
+}
+
+func parseUnstructured(kubeObj string) (*unstructured.Unstructured, error) {
+	kubeJson, err := yaml.ToJSON([]byte(kubeObj))
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(kubeJson); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
@@ -0,0 +1,110 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// ErrMissingSelector is wrapped in the ObjectError of a workload whose
+// spec.selector is missing but needed to build its template.
+var ErrMissingSelector = errors.New("spec.selector is missing")
+
+// ObjectError is returned when a single Kubernetes object can not be turned
+// into a chart template. Kind and Name identify the object (they are empty if
+// the object could not be parsed at all) and Source tells where it was read
+// from, when known.
+type ObjectError struct {
+	Kind   string
+	Name   string
+	Source string
+	Err    error
+}
+
+func (e *ObjectError) Error() string {
+	return fmt.Sprintf("%s: %v", describeObject(e.Kind, e.Name, e.Source), e.Err)
+}
+
+// describeObject formats an object for messages, e.g. Deployment "web" (web.yaml).
+func describeObject(kind, name, source string) string {
+	var obj string
+	switch {
+	case len(kind) != 0 && len(name) != 0:
+		obj = fmt.Sprintf("%s %q", kind, name)
+	case len(kind) != 0:
+		obj = kind
+	default:
+		obj = "object"
+	}
+	if len(source) != 0 {
+		obj = fmt.Sprintf("%s (%s)", obj, source)
+	}
+	return obj
+}
+
+func (e *ObjectError) Unwrap() error {
+	return e.Err
+}
+
+// ObjectErrors collects the objects skipped by Generator.Create when
+// ContinueOnError is set.
+type ObjectErrors []*ObjectError
+
+func (e ObjectErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d object(s) skipped:\n  %s", len(e), strings.Join(msgs, "\n  "))
+}
+
+// Mismatch is a field of an input object that the chart generated from it
+// does not reproduce when rendered with its default values. Rendered is nil
+// when the field is missing from the rendered object, and Field is empty when
+// the whole object is.
+type Mismatch struct {
+	Kind     string
+	Name     string
+	Field    string
+	Source   interface{}
+	Rendered interface{}
+}
+
+func (m *Mismatch) Error() string {
+	obj := describeObject(m.Kind, m.Name, "")
+	switch {
+	case len(m.Field) == 0:
+		return fmt.Sprintf("%s: not rendered", obj)
+	case m.Rendered == nil:
+		return fmt.Sprintf("%s: %s lost, was %v", obj, m.Field, m.Source)
+	default:
+		return fmt.Sprintf("%s: %s changed from %v to %v", obj, m.Field, m.Source, m.Rendered)
+	}
+}
+
+// Mismatches collects the differences found by VerifyChart.
+type Mismatches []*Mismatch
+
+func (e Mismatches) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d difference(s) between the chart and its objects:\n  %s", len(e), strings.Join(msgs, "\n  "))
+}
+
+// LintError holds the findings of LintChart when at least one of them is an
+// error.
+type LintError []support.Message
+
+func (e LintError) Error() string {
+	var msgs []string
+	for _, msg := range e {
+		if msg.Severity >= support.ErrorSev {
+			msgs = append(msgs, msg.Error())
+		}
+	}
+	return fmt.Sprintf("%d lint error(s):\n  %s", len(msgs), strings.Join(msgs, "\n  "))
+}
@@ -2,11 +2,11 @@ package pkg
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -18,238 +18,289 @@ import (
 	batch "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	networking "k8s.io/api/networking/v1"
+	rbac "k8s.io/api/rbac/v1"
 	storage "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 type Generator struct {
 	Location  string
 	ChartName string
 	YamlFiles []string
+	// YamlSources optionally records where each entry of YamlFiles was read
+	// from (e.g. a file path). It is only used to annotate errors.
+	YamlSources []string
+	// ContinueOnError skips objects that can not be converted instead of
+	// aborting. The skipped objects are returned as ObjectErrors after the
+	// rest of the chart has been written.
+	ContinueOnError bool
+	// Handlers are tried in order before the built-in handlers, see
+	// KindHandler.
+	Handlers []KindHandler
+	// Chart holds Chart.yaml fields to use instead of the defaults. Its
+	// name is always ChartName.
+	Chart chart.Metadata
+	// AppVersionFromImage sets the appVersion, when Chart has none, to the
+	// tag of the image of the first container in YamlFiles.
+	AppVersionFromImage bool
+	// Lint runs Helm's chart linter on the chart after creating it, see
+	// LintChart.
+	Lint bool
+	// Verify renders the chart after creating it and compares it with the
+	// objects it was generated from, see VerifyChart.
+	Verify bool
+	// PackageDir, when set, is where the chart is archived after it has
+	// been created without errors, see PackageChart.
+	PackageDir string
+	// Index regenerates the index.yaml of PackageDir after packaging the
+	// chart, making it a local chart repository. The charts are listed
+	// under IndexURL, or relative to the index when it is empty.
+	Index    bool
+	IndexURL string
+	// Output, when set, receives the files of the chart instead of the chart
+	// directory in Location, e.g. a StreamWriter for a dry run. Nothing is
+	// written to disk then.
+	Output ChartWriter
+	// TrackChanges keeps a copy of the generated files in the GeneratedDir
+	// of the chart, for Update to tell the edits made to the chart from what
+	// chartify generated. Update always does.
+	TrackChanges bool
 }
 
 var (
 	ChartObject      map[string][]string
-	chnageObjectType = []string{"Secret", "Configmap", "PersistentVolume", "PersistentVolumeClaim"}
+	chnageObjectType = []string{"Secret", "Configmap", "PersistentVolume", "PersistentVolumeClaim", "Service",
+		"ServiceAccount", "Role", "RoleBinding", "ClusterRole", "ClusterRoleBinding"}
 )
 
 func (g Generator) Create() (string, error) {
-	chartfile := chartMetaData(g.ChartName)
-	imageTag := "" // TODO
-	fmt.Println("Creating chart...")
-	cdir := filepath.Join(g.Location, chartfile.Name)
-	fi, err := os.Stat(cdir)
-	if err == nil && !fi.IsDir() {
-		return cdir, fmt.Errorf("%s already exists and is not a directory", cdir)
+	if g.Output != nil && (g.Lint || g.Verify || len(g.PackageDir) != 0) {
+		return "", errors.New("lint, verify and package need the chart on disk, they can not be used with Output")
 	}
-	ChartObject = getInsideObjects(g.YamlFiles)
-	if err := os.MkdirAll(cdir, 0o755); err != nil {
+	fmt.Fprintln(LogOutput, "Creating chart...")
+	cdir, generated, skipped, err := g.generate()
+	if err != nil {
 		return cdir, err
 	}
-	cf := filepath.Join(cdir, ChartfileName)
-	if _, err := os.Stat(cf); err != nil {
-		if len(chartfile.Version) == 0 {
-			chartfile.Version = imageTag
+	return cdir, g.finish("CREATE", cdir, generated, skipped)
+}
+
+// generate writes the chart into Location, or to Output if set. It returns the objects it made
+// templates of and, with ContinueOnError, the objects it skipped.
+func (g Generator) generate() (string, []string, ObjectErrors, error) {
+	chartfile := g.chartMetaData()
+	if err := chartfile.Validate(); err != nil {
+		return "", nil, nil, err
+	}
+	cdir := filepath.Join(g.Location, chartfile.Name)
+	out := g.Output
+	writeChartfile := true
+	if out == nil {
+		fi, err := os.Stat(cdir)
+		if err == nil && !fi.IsDir() {
+			return cdir, nil, nil, fmt.Errorf("%s already exists and is not a directory", cdir)
 		}
-		if err := SaveChartfile(cf, &chartfile); err != nil {
-			return cdir, err
+		out = dirWriter(cdir)
+		if _, err := os.Stat(filepath.Join(cdir, ChartfileName)); err == nil {
+			writeChartfile = false
 		}
 	}
-
-	valueFile := make(map[string]interface{}, 0)
-	persistence := make(map[string]interface{}, 0)
-	templateLocation := filepath.Join(cdir, TemplatesDir)
-	err = os.MkdirAll(templateLocation, 0o755)
-
-	for _, kubeObj := range g.YamlFiles {
-		kubeJson, err := yaml.ToJSON([]byte(kubeObj))
+	ChartObject = getInsideObjects(g.YamlFiles)
+	if writeChartfile {
+		data, err := ylib.Marshal(&chartfile)
 		if err != nil {
-			log.Fatal(err)
+			return cdir, nil, nil, err
 		}
-
-		var objMeta metav1.TypeMeta
-		if err := json.Unmarshal(kubeJson, &objMeta); err != nil {
-			log.Fatal(err)
+		if err := out.WriteFile(ChartfileName, data); err != nil {
+			return cdir, nil, nil, err
 		}
+	}
 
-		values := valueFileGenerator{}
-		var template, templateName string
-
-		if objMeta.Kind == "Pod" {
-			pod := apiv1.Pod{}
-			if err := json.Unmarshal(kubeJson, &pod); err != nil {
-				log.Fatal(err)
-			}
-
-			templateName = filepath.Join(templateLocation, ".pod.yaml")
-			template, values = podTemplate(pod)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-			persistence = addPersistence(persistence, values.persistence)
-
-		} else if objMeta.Kind == "ReplicationController" {
-			rc := apiv1.ReplicationController{}
-			if err := json.Unmarshal(kubeJson, &rc); err != nil {
-				log.Fatal(err)
-			}
-
-			templateName = filepath.Join(templateLocation, ".rc.yaml")
-			template, values = replicationControllerTemplate(rc)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-			persistence = addPersistence(persistence, values.persistence)
-
-		} else if objMeta.Kind == "Deployment" {
-			deployment := appsv1.Deployment{}
-			if err := json.Unmarshal(kubeJson, &deployment); err != nil {
-				log.Fatal(err)
-			}
-
-			templateName = filepath.Join(templateLocation, ".deployment.yaml")
-			template, values = deploymentTemplate(deployment)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-			persistence = addPersistence(persistence, values.persistence)
-
-		} else if objMeta.Kind == "Job" {
-			job := batch.Job{}
-			if err := json.Unmarshal(kubeJson, &job); err != nil {
-				log.Fatal(err)
-			}
-
-			templateName = filepath.Join(templateLocation, ".job.yaml")
-			template, values = jobTemplate(job)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-			persistence = addPersistence(persistence, values.persistence)
-
-		} else if objMeta.Kind == "DaemonSet" {
-			daemonset := extensions.DaemonSet{}
-			if err := json.Unmarshal(kubeJson, &daemonset); err != nil {
-				log.Fatal(err)
-			}
-
-			templateName = filepath.Join(templateLocation, ".daemonset.yaml")
-			template, values = daemonsetTemplate(daemonset)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-			persistence = addPersistence(persistence, values.persistence)
-
-		} else if objMeta.Kind == "ReplicaSet" {
-			rcSet := extensions.ReplicaSet{}
-			if err := json.Unmarshal(kubeJson, &rcSet); err != nil {
-				log.Fatal(err)
-			}
-
-			templateName = filepath.Join(templateLocation, ".rs.yaml")
-			template, values = replicaSetTemplate(rcSet)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-			persistence = addPersistence(persistence, values.persistence)
-
-		} else if objMeta.Kind == "StatefulSet" {
-			statefulset := appsv1.StatefulSet{}
-			if err := json.Unmarshal(kubeJson, &statefulset); err != nil {
-				log.Fatal(err)
-			}
-
-			templateName = filepath.Join(templateLocation, ".statefulset.yaml")
-			template, values = statefulsetTemplate(statefulset)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-			persistence = addPersistence(persistence, values.persistence)
-
-		} else if objMeta.Kind == "Service" {
-			service := apiv1.Service{}
-			if err := json.Unmarshal(kubeJson, &service); err != nil {
-				log.Fatal(err)
-			}
-			template, values = serviceTemplate(service)
-
-			templateName = filepath.Join(templateLocation, ".svc.yaml")
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-			persistence = addPersistence(persistence, values.persistence)
-
-		} else if objMeta.Kind == "ConfigMap" {
-			configMap := apiv1.ConfigMap{}
-			if err := json.Unmarshal(kubeJson, &configMap); err != nil {
-				log.Fatal(err)
-			}
-
-			templateName = filepath.Join(templateLocation, ".yaml")
-			template, values = configMapTemplate(configMap)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
+	valueFile := make(map[string]interface{}, 0)
+	persistence := make(map[string]interface{}, 0)
 
-		} else if objMeta.Kind == "Secret" {
-			secret := apiv1.Secret{}
-			if err := json.Unmarshal(kubeJson, &secret); err != nil {
-				log.Fatal(err)
+	var (
+		skipped   ObjectErrors
+		generic   []string
+		generated []string
+	)
+	for i, kubeObj := range g.YamlFiles {
+		kind, name, err := getObjectKindAndName(kubeObj)
+		if err == nil {
+			var isGeneric bool
+			isGeneric, err = g.createTemplate(kubeObj, chartfile.Name, out, valueFile, persistence)
+			if err == nil && isGeneric {
+				generic = append(generic, describeObject(kind, name, g.source(i)))
 			}
-
-			templateName = filepath.Join(templateLocation, ".secret.yaml")
-			template, values = secretTemplate(secret)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-
-		} else if objMeta.Kind == "PersistentVolumeClaim" {
-			pvc := apiv1.PersistentVolumeClaim{}
-			if err := json.Unmarshal(kubeJson, &pvc); err != nil {
-				log.Fatal(err)
+			if err == nil {
+				generated = append(generated, kubeObj)
 			}
-
-			templateName = filepath.Join(templateLocation, ".pvc.yaml")
-			template, values = pvcTemplate(pvc)
-			persistence = addPersistence(persistence, values.persistence)
-
-		} else if objMeta.Kind == "PersistentVolume" {
-			pv := apiv1.PersistentVolume{}
-			if err := json.Unmarshal(kubeJson, &pv); err != nil {
-				log.Fatal(err)
+		}
+		if err != nil {
+			objErr := &ObjectError{Kind: kind, Name: name, Source: g.source(i), Err: err}
+			if !g.ContinueOnError {
+				return cdir, nil, nil, objErr
 			}
+			skipped = append(skipped, objErr)
+		}
+	}
+	if len(generic) != 0 {
+		fmt.Fprintln(LogOutput, "No dedicated generator for these objects, only their metadata is parameterized:")
+		for _, obj := range generic {
+			fmt.Fprintln(LogOutput, "  "+obj)
+		}
+	}
+	for _, dep := range crdDependents(g.YamlFiles) {
+		fmt.Fprintln(LogOutput, dep)
+	}
+	if len(persistence) != 0 {
+		valueFile[Persistence] = persistence
+	}
+	valueFile[NameOverride] = ""
+	valueFile[FullnameOverride] = ""
+	valueFile[CommonLabels] = map[string]interface{}{}
+	valueFile[CommonAnnotations] = map[string]interface{}{}
+	if len(ChartObject["ServiceAccount"]) != 0 {
+		valueFile[ServiceAccount] = map[string]interface{}{Create: true}
+	}
+	for _, kind := range []string{"Role", "RoleBinding", "ClusterRole", "ClusterRoleBinding"} {
+		if len(ChartObject[kind]) != 0 {
+			valueFile[RBAC] = map[string]interface{}{Create: true}
+			break
+		}
+	}
+	valueFileData, err := ylib.Marshal(valueFile)
+	if err != nil {
+		return cdir, nil, nil, err
+	}
+	if err := out.WriteFile(path.Join(TemplatesDir, HelpersName), []byte(chartTemplate(defaultHelpers, chartfile.Name))); err != nil {
+		return cdir, nil, nil, err
+	}
+	notes, err := notesTemplate(generated)
+	if err != nil {
+		return cdir, nil, nil, err
+	}
+	if len(notes) != 0 {
+		if err := out.WriteFile(path.Join(TemplatesDir, NotesName), []byte(chartTemplate(notes, chartfile.Name))); err != nil {
+			return cdir, nil, nil, err
+		}
+	}
+	if err := out.WriteFile(ValuesfileName, valueFileData); err != nil {
+		return cdir, nil, nil, err
+	}
+	schemaData, err := valuesSchema(valueFileData)
+	if err != nil {
+		return cdir, nil, nil, err
+	}
+	if err := out.WriteFile(SchemafileName, schemaData); err != nil {
+		return cdir, nil, nil, err
+	}
+	if g.Output == nil && g.TrackChanges {
+		if err := saveGenerated(cdir); err != nil {
+			return cdir, nil, nil, err
+		}
+	}
+	return cdir, generated, skipped, nil
+}
 
-			templateName = filepath.Join(templateLocation, ".pv.yaml")
-			template, values = pvTemplate(pv)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-
-		} else if objMeta.Kind == "StorageClass" {
-			storageClass := storage.StorageClass{}
-			if err := json.Unmarshal(kubeJson, &storageClass); err != nil {
-				log.Fatal(err)
+// finish runs the checks asked for on the chart written by generate and
+// packages it, unless objects were skipped. op names the command in messages.
+func (g Generator) finish(op string, cdir string, generated []string, skipped ObjectErrors) error {
+	if g.Lint {
+		if err := LintChart(cdir); err != nil {
+			return err
+		}
+	}
+	if g.Verify {
+		if err := VerifyChart(cdir, generated); err != nil {
+			return err
+		}
+	}
+	if len(skipped) != 0 {
+		fmt.Fprintln(LogOutput, op+" : COMPLETED WITH ERRORS")
+		return skipped
+	}
+	if len(g.PackageDir) != 0 {
+		if _, err := PackageChart(cdir, g.PackageDir); err != nil {
+			return err
+		}
+		if g.Index {
+			if err := IndexRepository(g.PackageDir, g.IndexURL); err != nil {
+				return err
 			}
+		}
+	}
+	fmt.Fprintln(LogOutput, op+" : SUCCESSFUL")
+	return nil
+}
 
-			templateName = filepath.Join(templateLocation, ".storage.yaml")
-			template, values = storageClassTemplate(storageClass)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
+// source returns where the i-th object of YamlFiles was read from.
+func (g Generator) source(i int) string {
+	if i < len(g.YamlSources) {
+		return g.YamlSources[i]
+	}
+	return ""
+}
 
-		} else if objMeta.Kind == "HorizontalPodAutoscaler" {
-			podAutoscaler := v1.HorizontalPodAutoscaler{}
-			if err := json.Unmarshal(kubeJson, &podAutoscaler); err != nil {
-				log.Fatal(err)
-			}
+// createTemplate writes the template for kubeObj and merges its values. It
+// reports whether no handler matched the object and it only got the generic
+// metadata template.
+func (g Generator) createTemplate(kubeObj string, chartName string, out ChartWriter, valueFile map[string]interface{}, persistence map[string]interface{}) (bool, error) {
+	kubeJson, err := yaml.ToJSON([]byte(kubeObj))
+	if err != nil {
+		return false, err
+	}
 
-			templateName = filepath.Join(templateLocation, ".hpa.yaml")
-			template, values = horizontalPodAutoscaler(podAutoscaler)
-			values.MergeInto(valueFile, generateSafeKey(objMeta.Kind))
-			persistence = addPersistence(persistence, values.persistence)
+	var objMeta metav1.TypeMeta
+	if err := json.Unmarshal(kubeJson, &objMeta); err != nil {
+		return false, err
+	}
 
-		} else {
-			fmt.Printf("%v is not supported. Please add manually. Consider filing bug here: https://github.com/damarseta/chartify/issues\n", objMeta.Kind)
-			continue
-		}
+	var (
+		template *Template
+		generic  bool
+	)
+	if h := g.handlerFor(objMeta.GroupVersionKind()); h != nil {
+		template, err = h.Generate(kubeJson)
+	} else {
+		template, err = genericTemplate(kubeJson)
+		generic = true
+	}
+	if err != nil {
+		return false, err
+	}
 
-		if err := ioutil.WriteFile(templateName, []byte(template), 0o644); err != nil {
-			log.Fatalf("ERR: '%s' - %s", templateName, err)
-		}
+	if key := generateSafeKey(template.Name); template.Values != nil && reservedValues[key] {
+		return false, fmt.Errorf("values key %q is reserved for the values of the chart", key)
 	}
-	if len(persistence) != 0 {
-		valueFile["persistence"] = persistence
+
+	dir, fileName, content := TemplatesDir, template.Name+".yaml", chartTemplate(template.Content, chartName)
+	if len(template.Dir) != 0 {
+		// only templates include helpers
+		dir, content = template.Dir, template.Content
 	}
-	valueFileData, err := ylib.Marshal(valueFile)
-	if err != nil {
-		log.Fatal(err)
+	if len(template.Suffix) != 0 {
+		fileName = template.Name + "." + template.Suffix + ".yaml"
 	}
-	helperDir := filepath.Join(templateLocation, HelpersName)
-	if err := ioutil.WriteFile(helperDir, []byte(defaultHelpers), 0o644); err != nil {
-		log.Fatal(err)
+	templateName := path.Join(dir, fileName)
+	if err := out.WriteFile(templateName, []byte(content)); err != nil {
+		return false, fmt.Errorf("writing %s: %v", templateName, err)
 	}
-	valueDir := filepath.Join(cdir, ValuesfileName)
-	if err := ioutil.WriteFile(valueDir, valueFileData, 0o644); err != nil {
-		log.Fatal(err)
+	if template.Values != nil {
+		values := valueFileGenerator{value: map[string]interface{}{generateSafeKey(template.Suffix): template.Values}}
+		values.MergeInto(valueFile, generateSafeKey(template.Name))
 	}
-	fmt.Println("CREATE : SUCCESSFUL")
-	return cdir, nil
+	addPersistence(persistence, template.Persistence)
+	return generic, nil
+}
+
+// reservedValues are the top-level values of the chart itself, which the
+// values of objects must not overwrite.
+var reservedValues = map[string]bool{
+	Persistence: true,
+	RBAC:        true,
 }
 
 func cleanUpObjectMeta(m *metav1.ObjectMeta) {
@@ -261,19 +312,39 @@ func cleanUpObjectMeta(m *metav1.ObjectMeta) {
 	m.DeletionTimestamp = nil
 }
 
+// decorators are the labels and annotations that the API server and its
+// controllers add to objects.
+var decorators = []string{
+	"controller-uid",
+	"deployment.kubernetes.io/desired-replicas",
+	"deployment.kubernetes.io/max-replicas",
+	"deployment.kubernetes.io/revision",
+	"pod-template-hash",
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+}
+
 func cleanUpDecorators(m map[string]string) {
-	delete(m, "controller-uid")
-	delete(m, "deployment.kubernetes.io/desired-replicas")
-	delete(m, "deployment.kubernetes.io/max-replicas")
-	delete(m, "deployment.kubernetes.io/revision")
-	delete(m, "pod-template-hash")
-	delete(m, "pv.kubernetes.io/bind-completed")
-	delete(m, "pv.kubernetes.io/bound-by-controller")
+	for _, k := range decorators {
+		delete(m, k)
+	}
+}
+
+func isDecorator(k string) bool {
+	for _, d := range decorators {
+		if k == d {
+			return true
+		}
+	}
+	return false
 }
 
 func cleanUpPodSpec(p *apiv1.PodSpec) {
 	p.DNSPolicy = ""
 	p.NodeName = ""
+	if p.DeprecatedServiceAccount == p.ServiceAccountName || p.DeprecatedServiceAccount == "default" {
+		p.DeprecatedServiceAccount = ""
+	}
 	if p.ServiceAccountName == "default" {
 		p.ServiceAccountName = ""
 	}
@@ -288,281 +359,661 @@ func cleanUpPodSpec(p *apiv1.PodSpec) {
 	}
 }
 
-func podTemplate(pod apiv1.Pod) (string, valueFileGenerator) {
+func podTemplate(pod apiv1.Pod) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&pod.ObjectMeta)
 	cleanUpPodSpec(&pod.Spec)
-	volumes := ""
+	var volumes []interface{}
 	value := make(map[string]interface{}, 0)
 	persistence := make(map[string]interface{}, 0)
-	key := generateSafeKey(pod.ObjectMeta.Name)
+	key := valueKey(pod.ObjectMeta.Name, "pod")
 	pod.ObjectMeta = generateObjectMetaTemplate(pod.ObjectMeta, key, value, pod.ObjectMeta.Name)
-	pod.Spec = generateTemplateForPodSpec(pod.Spec, key, value)
+	podSpec, err := generateTemplateForPodSpec(pod.Spec, key, value)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	pod.Spec = podSpec
 	if len(pod.Spec.Volumes) != 0 {
-		volumes, persistence = generateTemplateForVolume(pod.Spec.Volumes, key, value)
+		var err error
+		volumes, persistence, err = generateTemplateForVolume(pod.Spec.Volumes, key, value)
+		if err != nil {
+			return "", valueFileGenerator{}, err
+		}
 		pod.Spec.Volumes = nil
 	}
-	tempPodByte, err := ylib.Marshal(pod)
+	tw, err := newTemplateWriter(pod)
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
 	}
-	tempPod := removeEmptyFields(string(tempPodByte))
-	template := ""
-	if len(volumes) != 0 {
-		template = addVolumeToTemplateForPod(tempPod, volumes)
-	} else {
-		template = tempPod
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setPodSpecInTemplate(tw, pod.Spec, volumes, key, podSpecPath...); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	template, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
 	}
 	data := valueFileGenerator{
 		value:       value,
 		persistence: persistence,
 	}
-	return template, data
+	return template, data, nil
 }
 
-func replicationControllerTemplate(rc apiv1.ReplicationController) (string, valueFileGenerator) {
+func replicationControllerTemplate(rc apiv1.ReplicationController) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&rc.ObjectMeta)
 	cleanUpPodSpec(&rc.Spec.Template.Spec)
-	volumes := ""
+	var volumes []interface{}
 	value := make(map[string]interface{}, 0)
 	persistence := make(map[string]interface{}, 0)
-	key := generateSafeKey(rc.ObjectMeta.Name)
+	key := valueKey(rc.ObjectMeta.Name, "rc")
 	rc.ObjectMeta = generateObjectMetaTemplate(rc.ObjectMeta, key, value, rc.ObjectMeta.Name)
-	rc.Spec.Template.Spec = generateTemplateForPodSpec(rc.Spec.Template.Spec, key, value)
+	podSpec, err := generateTemplateForPodSpec(rc.Spec.Template.Spec, key, value)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	rc.Spec.Template.Spec = podSpec
+	rc.Spec.Template.ObjectMeta = generatePodTemplateMetaTemplate(rc.Spec.Template.ObjectMeta, value)
 	if len(rc.Spec.Template.Spec.Volumes) != 0 {
-		volumes, persistence = generateTemplateForVolume(rc.Spec.Template.Spec.Volumes, key, value)
+		var err error
+		volumes, persistence, err = generateTemplateForVolume(rc.Spec.Template.Spec.Volumes, key, value)
+		if err != nil {
+			return "", valueFileGenerator{}, err
+		}
 		value[Persistence] = true
 		rc.Spec.Template.Spec.Volumes = nil
 	}
-	tempRcByte, err := ylib.Marshal(rc)
+	tw, err := newTemplateWriter(rc)
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if len(rc.Spec.Selector) != 0 {
+		if err := setSelectorInTemplate(tw, "spec", "selector"); err != nil {
+			return "", valueFileGenerator{}, err
+		}
 	}
-	tempRc := removeEmptyFields(string(tempRcByte))
 
-	tempRc, value = generateTemplateReplicationCtrSpec(rc.Spec, tempRc, key, value)
+	if err := generateTemplateReplicationCtrSpec(rc.Spec, tw, key, value); err != nil {
+		return "", valueFileGenerator{}, err
+	}
 
-	template := ""
-	if len(volumes) != 0 {
-		template = addVolumeToTemplate(tempRc, volumes)
-	} else {
-		template = tempRc
+	if err := setPodSpecInTemplate(tw, rc.Spec.Template.Spec, volumes, key, templateSpecPath...); err != nil {
+		return "", valueFileGenerator{}, err
 	}
-	return template, valueFileGenerator{value: value, persistence: persistence}
+	template, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	return template, valueFileGenerator{value: value, persistence: persistence}, nil
 }
 
-func replicaSetTemplate(replicaSet extensions.ReplicaSet) (string, valueFileGenerator) {
+func replicaSetTemplate(replicaSet extensions.ReplicaSet) (string, valueFileGenerator, error) {
+	if replicaSet.Spec.Selector == nil {
+		return "", valueFileGenerator{}, ErrMissingSelector
+	}
 	cleanupForReplicaSets(&replicaSet)
-	volumes := ""
+	var volumes []interface{}
 	value := make(map[string]interface{}, 0)
 	persistence := make(map[string]interface{}, 0)
-	key := generateSafeKey(replicaSet.ObjectMeta.Name)
+	key := valueKey(replicaSet.ObjectMeta.Name, "rs")
 	replicaSet.ObjectMeta = generateObjectMetaTemplate(replicaSet.ObjectMeta, key, value, replicaSet.ObjectMeta.Name)
-	replicaSet.Spec.Template.Spec = generateTemplateForPodSpec(replicaSet.Spec.Template.Spec, key, value)
+	podSpec, err := generateTemplateForPodSpec(replicaSet.Spec.Template.Spec, key, value)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	replicaSet.Spec.Template.Spec = podSpec
+	replicaSet.Spec.Template.ObjectMeta = generatePodTemplateMetaTemplate(replicaSet.Spec.Template.ObjectMeta, value)
 	if len(replicaSet.Spec.Template.Spec.Volumes) != 0 {
-		volumes, persistence = generateTemplateForVolume(replicaSet.Spec.Template.Spec.Volumes, key, value)
+		var err error
+		volumes, persistence, err = generateTemplateForVolume(replicaSet.Spec.Template.Spec.Volumes, key, value)
+		if err != nil {
+			return "", valueFileGenerator{}, err
+		}
 		value[Persistence] = true
 		replicaSet.Spec.Template.Spec.Volumes = nil
 	}
-	if replicaSet.Spec.Selector != nil {
-		modifyLabelSelector(replicaSet.Spec.Selector, replicaSet.Spec.Template.Labels, replicaSet.ObjectMeta.Labels)
-	}
-	template := ""
-	tempRcSetByte, err := ylib.Marshal(replicaSet)
+	tw, err := newTemplateWriter(replicaSet)
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if replicaSet.Spec.Selector != nil && len(replicaSet.Spec.Selector.MatchLabels) != 0 {
+		if err := setSelectorInTemplate(tw, "spec", "selector", "matchLabels"); err != nil {
+			return "", valueFileGenerator{}, err
+		}
 	}
-	tempReplicaSet := removeEmptyFields(string(tempRcSetByte))
 
-	tempReplicaSet, value = generateTemplateReplicaSetSpec(replicaSet.Spec, tempReplicaSet, key, value)
+	if err := generateTemplateReplicaSetSpec(replicaSet.Spec, tw, key, value); err != nil {
+		return "", valueFileGenerator{}, err
+	}
 
-	if len(volumes) != 0 {
-		template = addVolumeToTemplate(tempReplicaSet, volumes) // RC and replica_set has volume in same layer
-	} else {
-		template = tempReplicaSet
+	if err := setPodSpecInTemplate(tw, replicaSet.Spec.Template.Spec, volumes, key, templateSpecPath...); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	template, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
 	}
 	return template, valueFileGenerator{
 		value:       value,
 		persistence: persistence,
-	}
+	}, nil
 }
 
-func deploymentTemplate(deployment appsv1.Deployment) (string, valueFileGenerator) {
+func deploymentTemplate(deployment appsv1.Deployment) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&deployment.ObjectMeta)
 	cleanUpPodSpec(&deployment.Spec.Template.Spec)
 	cleanUpDecorators(deployment.ObjectMeta.Annotations)
-	volumes := ""
+	var volumes []interface{}
 	value := make(map[string]interface{}, 0)
 	persistence := make(map[string]interface{}, 0)
-	key := generateSafeKey(deployment.ObjectMeta.Name)
+	key := valueKey(deployment.ObjectMeta.Name, "deployment")
 	deployment.ObjectMeta = generateObjectMetaTemplate(deployment.ObjectMeta, key, value, deployment.ObjectMeta.Name)
-	deployment.Spec.Template.Spec = generateTemplateForPodSpec(deployment.Spec.Template.Spec, key, value)
+	podSpec, err := generateTemplateForPodSpec(deployment.Spec.Template.Spec, key, value)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	deployment.Spec.Template.Spec = podSpec
+	deployment.Spec.Template.ObjectMeta = generatePodTemplateMetaTemplate(deployment.Spec.Template.ObjectMeta, value)
 	if len(deployment.Spec.Template.Spec.Volumes) != 0 {
-		volumes, persistence = generateTemplateForVolume(deployment.Spec.Template.Spec.Volumes, key, value)
+		var err error
+		volumes, persistence, err = generateTemplateForVolume(deployment.Spec.Template.Spec.Volumes, key, value)
+		if err != nil {
+			return "", valueFileGenerator{}, err
+		}
 		deployment.Spec.Template.Spec.Volumes = nil
 	}
 
 	if len(string(deployment.Spec.Strategy.Type)) != 0 {
-		fmt.Println("if len(string(deployment.Spec.Strategy.Type)) != 0 {")
-		if len(string(deployment.Spec.Strategy.Type)) != 0 {
-			fmt.Println("if len(string(deployment.Spec.Strategy.Type)) != 0 {")
-			value[DeploymentStrategy] = deployment.Spec.Strategy.Type
+		value[DeploymentStrategy] = deployment.Spec.Strategy.Type
+		deployment.Spec.Strategy.Type = appsv1.DeploymentStrategyType(fmt.Sprintf("{{.Values.%s.%s}}", key, DeploymentStrategy))
+	}
 
-			deployment.Spec.Strategy.Type = appsv1.DeploymentStrategyType(fmt.Sprintf("{{.Values.%s.%s}}", key, DeploymentStrategy))
+	tw, err := newTemplateWriter(deployment)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if deployment.Spec.Selector != nil && len(deployment.Spec.Selector.MatchLabels) != 0 {
+		if err := setSelectorInTemplate(tw, "spec", "selector", "matchLabels"); err != nil {
+			return "", valueFileGenerator{}, err
 		}
 	}
 
-	if deployment.Spec.Selector != nil {
-		modifyLabelSelector(deployment.Spec.Selector, deployment.Spec.Template.Labels, deployment.ObjectMeta.Labels)
+	if err := generateTemplateDeplymentSpec(deployment.Spec, tw, key, value); err != nil {
+		return "", valueFileGenerator{}, err
 	}
 
-	template := ""
-	tempDeploymentByte, err := ylib.Marshal(deployment)
-	if err != nil {
-		log.Fatal(err)
+	if err := setPodSpecInTemplate(tw, deployment.Spec.Template.Spec, volumes, key, templateSpecPath...); err != nil {
+		return "", valueFileGenerator{}, err
 	}
-	tempDeployment := removeEmptyFields(string(tempDeploymentByte))
-
-	tempDeployment, value = generateTemplateDeplymentSpec(deployment.Spec, tempDeployment, key, value)
-
-	if len(volumes) != 0 {
-		template = addVolumeToTemplate(tempDeployment, volumes)
-	} else {
-		template = tempDeployment
+	template, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
 	}
 
-	return template, valueFileGenerator{value: value, persistence: persistence}
+	return template, valueFileGenerator{value: value, persistence: persistence}, nil
 }
 
-func daemonsetTemplate(daemonset extensions.DaemonSet) (string, valueFileGenerator) {
+func daemonsetTemplate(daemonset extensions.DaemonSet) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&daemonset.ObjectMeta)
 	cleanUpPodSpec(&daemonset.Spec.Template.Spec)
-	volumes := ""
+	var volumes []interface{}
 	value := make(map[string]interface{}, 0)
 	persistence := make(map[string]interface{}, 0)
-	key := generateSafeKey(daemonset.ObjectMeta.Name)
+	key := valueKey(daemonset.ObjectMeta.Name, "daemonset")
 	daemonset.ObjectMeta = generateObjectMetaTemplate(daemonset.ObjectMeta, key, value, daemonset.ObjectMeta.Name)
-	daemonset.Spec.Template.Spec = generateTemplateForPodSpec(daemonset.Spec.Template.Spec, key, value)
+	podSpec, err := generateTemplateForPodSpec(daemonset.Spec.Template.Spec, key, value)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	daemonset.Spec.Template.Spec = podSpec
+	daemonset.Spec.Template.ObjectMeta = generatePodTemplateMetaTemplate(daemonset.Spec.Template.ObjectMeta, value)
 	if len(daemonset.Spec.Template.Spec.Volumes) != 0 {
-		volumes, persistence = generateTemplateForVolume(daemonset.Spec.Template.Spec.Volumes, key, value)
+		var err error
+		volumes, persistence, err = generateTemplateForVolume(daemonset.Spec.Template.Spec.Volumes, key, value)
+		if err != nil {
+			return "", valueFileGenerator{}, err
+		}
 		value[Persistence] = true
 		daemonset.Spec.Template.Spec.Volumes = nil
 	}
 
-	if daemonset.Spec.Selector != nil {
-		modifyLabelSelector(daemonset.Spec.Selector, daemonset.Spec.Template.Labels, daemonset.ObjectMeta.Labels)
-	}
-
-	template := ""
-	tempDaemonSetByte, err := ylib.Marshal(daemonset)
+	tw, err := newTemplateWriter(daemonset)
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
 	}
-	tempDaemonSet := removeEmptyFields(string(tempDaemonSetByte))
-	if len(volumes) != 0 {
-		template = addVolumeToTemplate(tempDaemonSet, volumes)
-	} else {
-		template = tempDaemonSet
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if daemonset.Spec.Selector != nil && len(daemonset.Spec.Selector.MatchLabels) != 0 {
+		if err := setSelectorInTemplate(tw, "spec", "selector", "matchLabels"); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+	}
+	if err := setPodSpecInTemplate(tw, daemonset.Spec.Template.Spec, volumes, key, templateSpecPath...); err != nil {
+		return "", valueFileGenerator{}, err
 	}
-	return template, valueFileGenerator{value: value, persistence: persistence}
+	template, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	return template, valueFileGenerator{value: value, persistence: persistence}, nil
 }
 
-func statefulsetTemplate(statefulset appsv1.StatefulSet) (string, valueFileGenerator) {
+func statefulsetTemplate(statefulset appsv1.StatefulSet) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&statefulset.ObjectMeta)
 	cleanUpPodSpec(&statefulset.Spec.Template.Spec)
-	volumes := ""
+	cleanUpDecorators(statefulset.ObjectMeta.Annotations)
+	var volumes []interface{}
 	value := make(map[string]interface{}, 0)
 	persistence := make(map[string]interface{}, 0)
-	key := generateSafeKey(statefulset.ObjectMeta.Name)
+	key := valueKey(statefulset.ObjectMeta.Name, "statefulset")
 	statefulset.ObjectMeta = generateObjectMetaTemplate(statefulset.ObjectMeta, key, value, statefulset.ObjectMeta.Name)
 	if len(statefulset.Spec.ServiceName) != 0 {
 		value[ServiceName] = statefulset.Spec.ServiceName // generateTemplateForSingleValue(statefulset.Spec.ServiceName, "ServiceName", value)
 		statefulset.Spec.ServiceName = fmt.Sprintf("{{.Values.%s.%s}}", key, ServiceName)
 	}
-	statefulset.Spec.Template.Spec = generateTemplateForPodSpec(statefulset.Spec.Template.Spec, key, value)
-	if statefulset.Spec.Selector != nil {
-		modifyLabelSelector(statefulset.Spec.Selector, statefulset.Spec.Template.Labels, statefulset.ObjectMeta.Labels)
+	podSpec, err := generateTemplateForPodSpec(statefulset.Spec.Template.Spec, key, value)
+	if err != nil {
+		return "", valueFileGenerator{}, err
 	}
+	statefulset.Spec.Template.Spec = podSpec
+	statefulset.Spec.Template.ObjectMeta = generatePodTemplateMetaTemplate(statefulset.Spec.Template.ObjectMeta, value)
 	if len(statefulset.Spec.Template.Spec.Volumes) != 0 {
-		volumes, persistence = generateTemplateForVolume(statefulset.Spec.Template.Spec.Volumes, key, value)
+		var err error
+		volumes, persistence, err = generateTemplateForVolume(statefulset.Spec.Template.Spec.Volumes, key, value)
+		if err != nil {
+			return "", valueFileGenerator{}, err
+		}
 		statefulset.Spec.Template.Spec.Volumes = nil
 	}
-	tempStatefulSetByte, err := ylib.Marshal(statefulset)
+	tw, err := newTemplateWriter(statefulset)
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
 	}
-	tempStatefulSet := removeEmptyFields(string(tempStatefulSetByte))
-	template := ""
-	if len(volumes) != 0 {
-		template = addVolumeToTemplate(tempStatefulSet, volumes)
-	} else {
-		template = tempStatefulSet
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	// StatefulSets of apps/v1beta1 and older may leave the selector to be
+	// defaulted from the pod labels, which apps/v1 requires to be set
+	if statefulset.Spec.Selector == nil || len(statefulset.Spec.Selector.MatchLabels) != 0 {
+		if err := setSelectorInTemplate(tw, "spec", "selector", "matchLabels"); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+	}
+	if err := generateTemplateStatefulSetSpec(statefulset.Spec, tw, key, value); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setPodSpecInTemplate(tw, statefulset.Spec.Template.Spec, volumes, key, templateSpecPath...); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	template, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
 	}
-	return template, valueFileGenerator{value: value, persistence: persistence}
+	return template, valueFileGenerator{value: value, persistence: persistence}, nil
 }
 
-func jobTemplate(job batch.Job) (string, valueFileGenerator) {
+func jobTemplate(job batch.Job) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&job.ObjectMeta)
 	cleanUpPodSpec(&job.Spec.Template.Spec)
 	cleanUpDecorators(job.ObjectMeta.Labels)
 	cleanUpDecorators(job.Spec.Template.Labels)
-	cleanUpDecorators(job.Spec.Selector.MatchLabels)
-	volumes := ""
+	if job.Spec.Selector != nil {
+		cleanUpDecorators(job.Spec.Selector.MatchLabels)
+	}
+	var volumes []interface{}
 	persistence := make(map[string]interface{}, 0)
 	value := make(map[string]interface{}, 0)
-	key := generateSafeKey(job.ObjectMeta.Name)
+	key := valueKey(job.ObjectMeta.Name, "job")
 	job.ObjectMeta = generateObjectMetaTemplate(job.ObjectMeta, key, value, job.ObjectMeta.Name)
-	job.Spec.Template.Spec = generateTemplateForPodSpec(job.Spec.Template.Spec, key, value)
+	podSpec, err := generateTemplateForPodSpec(job.Spec.Template.Spec, key, value)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	job.Spec.Template.Spec = podSpec
+	job.Spec.Template.ObjectMeta = generatePodTemplateMetaTemplate(job.Spec.Template.ObjectMeta, value)
 	if len(job.Spec.Template.Spec.Volumes) != 0 {
-		volumes, persistence = generateTemplateForVolume(job.Spec.Template.Spec.Volumes, key, value)
+		var err error
+		volumes, persistence, err = generateTemplateForVolume(job.Spec.Template.Spec.Volumes, key, value)
+		if err != nil {
+			return "", valueFileGenerator{}, err
+		}
 		value[Persistence] = true
 		job.Spec.Template.Spec.Volumes = nil
 	}
-	if job.Spec.Selector != nil {
-		modifyLabelSelector(job.Spec.Selector, job.Spec.Template.Labels, job.ObjectMeta.Labels)
+	tw, err := newTemplateWriter(job)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setPodSpecInTemplate(tw, job.Spec.Template.Spec, volumes, key, templateSpecPath...); err != nil {
+		return "", valueFileGenerator{}, err
 	}
-	tempJobByte, err := ylib.Marshal(job)
+	template, err := tw.Render()
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
 	}
-	tempJob := removeEmptyFields(string(tempJobByte))
-	template := ""
-	if len(volumes) != 0 {
-		template = addVolumeToTemplate(tempJob, volumes)
-	} else {
-		template = tempJob
+	return template, valueFileGenerator{value: value, persistence: persistence}, nil
+}
+
+func cronJobTemplate(cronJob batch.CronJob) (string, valueFileGenerator, error) {
+	cleanUpObjectMeta(&cronJob.ObjectMeta)
+	jobSpec := &cronJob.Spec.JobTemplate.Spec
+	cleanUpPodSpec(&jobSpec.Template.Spec)
+	cleanUpDecorators(cronJob.ObjectMeta.Labels)
+	cleanUpDecorators(jobSpec.Template.Labels)
+	if jobSpec.Selector != nil {
+		cleanUpDecorators(jobSpec.Selector.MatchLabels)
+	}
+	var volumes []interface{}
+	persistence := make(map[string]interface{}, 0)
+	value := make(map[string]interface{}, 0)
+	key := valueKey(cronJob.ObjectMeta.Name, "cronjob")
+	cronJob.ObjectMeta = generateObjectMetaTemplate(cronJob.ObjectMeta, key, value, cronJob.ObjectMeta.Name)
+	podSpec, err := generateTemplateForPodSpec(jobSpec.Template.Spec, key, value)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	jobSpec.Template.Spec = podSpec
+	jobSpec.Template.ObjectMeta = generatePodTemplateMetaTemplate(jobSpec.Template.ObjectMeta, value)
+	if len(jobSpec.Template.Spec.Volumes) != 0 {
+		var err error
+		volumes, persistence, err = generateTemplateForVolume(jobSpec.Template.Spec.Volumes, key, value)
+		if err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		value[Persistence] = true
+		jobSpec.Template.Spec.Volumes = nil
 	}
-	return template, valueFileGenerator{value: value, persistence: persistence}
+	value[Schedule] = cronJob.Spec.Schedule
+	cronJob.Spec.Schedule = fmt.Sprintf("{{.Values.%s.%s}}", key, Schedule)
+	if len(cronJob.Spec.ConcurrencyPolicy) != 0 {
+		value[ConcurrencyPolicy] = cronJob.Spec.ConcurrencyPolicy
+		cronJob.Spec.ConcurrencyPolicy = batch.ConcurrencyPolicy(fmt.Sprintf("{{.Values.%s.%s}}", key, ConcurrencyPolicy))
+	}
+	tw, err := newTemplateWriter(cronJob)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := generateTemplateForCronJobSpec(cronJob.Spec, tw, key, value); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setPodSpecInTemplate(tw, jobSpec.Template.Spec, volumes, key, cronJobSpecPath...); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	template, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	return template, valueFileGenerator{value: value, persistence: persistence}, nil
 }
 
-func serviceTemplate(svc apiv1.Service) (string, valueFileGenerator) {
+func serviceTemplate(svc apiv1.Service) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&svc.ObjectMeta)
 	value := make(map[string]interface{}, 0)
-	key := generateSafeKey(svc.ObjectMeta.Name)
+	key := valueKey(svc.ObjectMeta.Name, "svc")
 	svc.ObjectMeta = generateObjectMetaTemplate(svc.ObjectMeta, key, value, svc.ObjectMeta.Name)
 	ip := net.ParseIP(svc.Spec.ClusterIP)
 	if ip != nil {
 		svc.Spec.ClusterIP = ""
 	}
 	svc.Spec = generateServiceSpecTemplate(svc.Spec, key, value)
-	if svc.Spec.Selector != nil {
-		svc.Spec.Selector = modifySvcLabelSelector(svc.Spec.Selector)
+	tw, err := newTemplateWriter(svc)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if len(svc.Spec.Selector) != 0 {
+		if err := setSelectorInTemplate(tw, "spec", "selector"); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+	}
+	service, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	return service, valueFileGenerator{value: value}, nil
+}
+
+func ingressTemplate(ingress networking.Ingress) (string, valueFileGenerator, error) {
+	cleanUpObjectMeta(&ingress.ObjectMeta)
+	value := make(map[string]interface{}, 0)
+	key := valueKey(ingress.ObjectMeta.Name, "ingress")
+	ingress.ObjectMeta = generateObjectMetaTemplate(ingress.ObjectMeta, key, value, ingress.ObjectMeta.Name)
+	spec, err := generateIngressSpecTemplate(ingress.Spec, key, value)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	ingress.Spec = spec
+	tw, err := newTemplateWriter(ingress)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setIngressRulesInTemplate(tw, key, value); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	temp, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	value[Enabled] = true
+	ingressTemplateData := fmt.Sprintf("{{- if .Values.%s.%s -}}\n%s{{- end -}}", key, Enabled, temp)
+	return ingressTemplateData, valueFileGenerator{value: value}, nil
+}
+
+func serviceAccountTemplate(serviceAccount apiv1.ServiceAccount) (string, valueFileGenerator, error) {
+	cleanUpObjectMeta(&serviceAccount.ObjectMeta)
+	value := make(map[string]interface{}, 0)
+	key := valueKey(serviceAccount.ObjectMeta.Name, "serviceaccount")
+	var secrets []apiv1.ObjectReference
+	for _, s := range serviceAccount.Secrets {
+		// token secrets are created by the token controller for every account
+		if strings.HasPrefix(s.Name, serviceAccount.Name+"-token-") {
+			continue
+		}
+		if !PreserveName && checkIfNameExist(s.Name, "Secret") {
+			s.Name = fmt.Sprintf("%s-%s", includeHelper("fullname"), s.Name)
+		}
+		secrets = append(secrets, s)
+	}
+	serviceAccount.Secrets = secrets
+	for i, s := range serviceAccount.ImagePullSecrets {
+		if !PreserveName && checkIfNameExist(s.Name, "Secret") {
+			serviceAccount.ImagePullSecrets[i].Name = fmt.Sprintf("%s-%s", includeHelper("fullname"), s.Name)
+		}
+	}
+	serviceAccount.ObjectMeta = generateObjectMetaTemplate(serviceAccount.ObjectMeta, key, value, serviceAccount.ObjectMeta.Name)
+	tw, err := newTemplateWriter(serviceAccount)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	temp, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	serviceAccountTemplateData := fmt.Sprintf("{{- if .Values.%s.%s -}}\n%s{{- end -}}", ServiceAccount, Create, temp)
+	return serviceAccountTemplateData, valueFileGenerator{value: value}, nil
+}
+
+func roleTemplate(role rbac.Role) (string, valueFileGenerator, error) {
+	cleanUpObjectMeta(&role.ObjectMeta)
+	value := make(map[string]interface{}, 0)
+	key := valueKey(role.ObjectMeta.Name, "role")
+	role.ObjectMeta = generateObjectMetaTemplate(role.ObjectMeta, key, value, role.ObjectMeta.Name)
+	rules := role.Rules
+	role.Rules = nil
+	return rbacTemplate(role, rules, key, value)
+}
+
+func roleBindingTemplate(roleBinding rbac.RoleBinding) (string, valueFileGenerator, error) {
+	cleanUpObjectMeta(&roleBinding.ObjectMeta)
+	value := make(map[string]interface{}, 0)
+	key := valueKey(roleBinding.ObjectMeta.Name, "rolebinding")
+	roleBinding.ObjectMeta = generateObjectMetaTemplate(roleBinding.ObjectMeta, key, value, roleBinding.ObjectMeta.Name)
+	roleBinding.Subjects = generateTemplateForSubjects(roleBinding.Subjects)
+	roleBinding.RoleRef = generateTemplateForRoleRef(roleBinding.RoleRef)
+	return rbacTemplate(roleBinding, nil, key, value)
+}
+
+func clusterRoleTemplate(clusterRole rbac.ClusterRole) (string, valueFileGenerator, error) {
+	cleanUpObjectMeta(&clusterRole.ObjectMeta)
+	cleanUpDecorators(clusterRole.ObjectMeta.Annotations)
+	value := make(map[string]interface{}, 0)
+	key := valueKey(clusterRole.ObjectMeta.Name, "clusterrole")
+	clusterRole.ObjectMeta = generateObjectMetaTemplate(clusterRole.ObjectMeta, key, value, clusterRole.ObjectMeta.Name)
+	rules := clusterRole.Rules
+	clusterRole.Rules = nil
+	return rbacTemplate(clusterRole, rules, key, value)
+}
+
+func clusterRoleBindingTemplate(clusterRoleBinding rbac.ClusterRoleBinding) (string, valueFileGenerator, error) {
+	cleanUpObjectMeta(&clusterRoleBinding.ObjectMeta)
+	value := make(map[string]interface{}, 0)
+	key := valueKey(clusterRoleBinding.ObjectMeta.Name, "clusterrolebinding")
+	clusterRoleBinding.ObjectMeta = generateObjectMetaTemplate(clusterRoleBinding.ObjectMeta, key, value, clusterRoleBinding.ObjectMeta.Name)
+	clusterRoleBinding.Subjects = generateTemplateForSubjects(clusterRoleBinding.Subjects)
+	clusterRoleBinding.RoleRef = generateTemplateForRoleRef(clusterRoleBinding.RoleRef)
+	return rbacTemplate(clusterRoleBinding, nil, key, value)
+}
+
+// rbacTemplate marshals an RBAC object and gates it behind rbac.create.
+// Rules are set after removeEmptyFields, which would otherwise drop the ""
+// core API group.
+func rbacTemplate(obj interface{}, rules []rbac.PolicyRule, key string, value map[string]interface{}) (string, valueFileGenerator, error) {
+	tw, err := newTemplateWriter(obj)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if len(rules) != 0 {
+		rulesNode, err := toTemplateNode(rules)
+		if err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		if err := tw.Set(rulesNode, "rules"); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+	}
+	temp, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	rbacTemplateData := fmt.Sprintf("{{- if .Values.%s.%s -}}\n%s{{- end -}}", RBAC, Create, temp)
+	return rbacTemplateData, valueFileGenerator{value: value}, nil
+}
+
+// genericTemplate templates the metadata of an object of a kind without a
+// handler and keeps the rest of it as is.
+func genericTemplate(kubeJson []byte) (*Template, error) {
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(kubeJson); err != nil {
+		return nil, err
+	}
+	metaData, err := json.Marshal(obj.Object["metadata"])
+	if err != nil {
+		return nil, err
+	}
+	var objectMeta metav1.ObjectMeta
+	if err := json.Unmarshal(metaData, &objectMeta); err != nil {
+		return nil, err
+	}
+	cleanUpObjectMeta(&objectMeta)
+	objectMeta.UID = ""
+	objectMeta.SelfLink = ""
+	objectMeta.ManagedFields = nil
+	delete(objectMeta.Annotations, "kubectl.kubernetes.io/last-applied-configuration")
+	value := make(map[string]interface{}, 0)
+	suffix := strings.ToLower(obj.GetKind())
+	key := valueKey(objectMeta.Name, suffix)
+	objectMeta = generateObjectMetaTemplate(objectMeta, key, value, objectMeta.Name)
+	metaData, err = ylib.Marshal(objectMeta)
+	if err != nil {
+		return nil, err
 	}
-	svcData, err := ylib.Marshal(svc)
+	// only metadata goes through removeEmptyFields, empty values in the rest
+	// of an unknown object may well be meaningful
+	temp, err := removeEmptyFields(string(metaData))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	metadata := make(map[string]interface{})
+	if err := ylib.Unmarshal([]byte(temp), &metadata); err != nil {
+		return nil, err
+	}
+	name := obj.GetName()
+	delete(obj.Object, "status")
+	obj.Object = escapeTemplate(obj.Object).(map[string]interface{})
+	obj.Object["metadata"] = metadata
+	tw := &templateWriter{object: obj.Object}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return nil, err
+	}
+	objData, err := tw.Render()
+	if err != nil {
+		return nil, err
+	}
+	return &Template{
+		Name:    name,
+		Suffix:  suffix,
+		Content: objData,
+		Values:  value,
+	}, nil
+}
+
+// templateEscaper quotes template delimiters so they are rendered as is.
+var templateEscaper = strings.NewReplacer("{{", "{{`{{`}}", "}}", "{{`}}`}}")
+
+// escapeTemplate quotes the template delimiters in the strings of v, which
+// unknown kinds such as alerting rules often carry for their own templating.
+func escapeTemplate(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return templateEscaper.Replace(v)
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = escapeTemplate(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = escapeTemplate(e)
+		}
 	}
-	service := removeEmptyFields(string(svcData))
-	return service, valueFileGenerator{value: value}
+	return v
 }
 
-func configMapTemplate(configMap apiv1.ConfigMap) (string, valueFileGenerator) {
+func configMapTemplate(configMap apiv1.ConfigMap) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&configMap.ObjectMeta)
 	value := make(map[string]interface{}, 0)
-	key := generateSafeKey(configMap.ObjectMeta.Name)
+	key := valueKey(configMap.ObjectMeta.Name, "configmap")
 	configMap.ObjectMeta = generateObjectMetaTemplate(configMap.ObjectMeta, key, value, configMap.ObjectMeta.Name)
-	configMapData, err := ylib.Marshal(configMap)
+	tw, err := newTemplateWriter(configMap)
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
 	}
 	if len(configMap.Data) != 0 {
 		for k, v := range configMap.Data {
@@ -570,15 +1021,21 @@ func configMapTemplate(configMap apiv1.ConfigMap) (string, valueFileGenerator) {
 			configMap.Data[k] = fmt.Sprintf("{{.Values.%s.%s}}", key, k)
 		}
 	}
-	data := removeEmptyFields(string(configMapData))
-	return data, valueFileGenerator{value: value}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	data, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	return data, valueFileGenerator{value: value}, nil
 }
 
-func secretTemplate(secret apiv1.Secret) (string, valueFileGenerator) {
+func secretTemplate(secret apiv1.Secret) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&secret.ObjectMeta)
 	value := make(map[string]interface{}, 0)
 	secretDataMap := make(map[string]interface{}, 0)
-	key := generateSafeKey(secret.ObjectMeta.Name)
+	key := valueKey(secret.ObjectMeta.Name, "secret")
 	secret.ObjectMeta = generateObjectMetaTemplate(secret.ObjectMeta, key, value, secret.ObjectMeta.Name)
 	if len(secret.Data) != 0 {
 		for k, v := range secret.Data {
@@ -586,26 +1043,34 @@ func secretTemplate(secret apiv1.Secret) (string, valueFileGenerator) {
 				// For values that starts with ".", the Values string get populated with ".." - error for helm
 				kmod := strings.Replace(k, ".", "", 1)
 				value[kmod] = v
-				secretDataMap[k] = fmt.Sprintf("{{.Values.%s.%s}}", key, kmod)
+				secretDataMap[k] = secretDataTemplate(key, kmod)
 			} else {
 				value[k] = v
-				secretDataMap[k] = fmt.Sprintf("{{.Values.%s.%s}}", key, k)
+				secretDataMap[k] = secretDataTemplate(key, k)
 			}
 		}
 	}
 	secret.Data = nil
 	value[Type] = secret.Type
 	secret.Type = apiv1.SecretType(fmt.Sprintf("{{.Values.%s.%s}}", key, Type))
-	secretDataByte, err := ylib.Marshal(secret)
+	tw, err := newTemplateWriter(secret)
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
 	}
-	secretData := removeEmptyFields(string(secretDataByte))
-	secretData = addSecretData(secretData, secretDataMap, key)
-	return secretData, valueFileGenerator{value: value}
+	if err := tw.Set(secretDataMap, "data"); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	secretData, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	return secretData, valueFileGenerator{value: value}, nil
 }
 
-func pvcTemplate(pvc apiv1.PersistentVolumeClaim) (string, valueFileGenerator) {
+func pvcTemplate(pvc apiv1.PersistentVolumeClaim) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&pvc.ObjectMeta)
 	cleanUpDecorators(pvc.ObjectMeta.Annotations)
 	tempValue := make(map[string]interface{}, 0)
@@ -614,91 +1079,99 @@ func pvcTemplate(pvc apiv1.PersistentVolumeClaim) (string, valueFileGenerator) {
 	key := Persistence + "." + rawKey
 	pvc.ObjectMeta = generateObjectMetaTemplate(pvc.ObjectMeta, key, tempValue, pvc.ObjectMeta.Name)
 	pvc.Spec = generatePersistentVolumeClaimSpec(pvc.Spec, key, tempValue)
-	pvcData, err := ylib.Marshal(pvc)
+	tw, err := newTemplateWriter(pvc)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	temp, err := tw.Render()
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
 	}
-	temp := removeEmptyFields(string(pvcData))
 	pvcTemplateData := fmt.Sprintf("{{- if .Values.%s.%s -}}\n%s{{- end -}}", key, Enabled, temp)
 	tempValue[Enabled] = true // By Default use persistence volume true
 	persistence[rawKey] = tempValue
-	return pvcTemplateData, valueFileGenerator{persistence: persistence}
+	return pvcTemplateData, valueFileGenerator{persistence: persistence}, nil
 }
 
-func pvTemplate(pv apiv1.PersistentVolume) (string, valueFileGenerator) {
+func pvTemplate(pv apiv1.PersistentVolume) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&pv.ObjectMeta)
 	value := make(map[string]interface{}, 0)
-	key := generateSafeKey(pv.ObjectMeta.Name)
+	key := valueKey(pv.ObjectMeta.Name, "pv")
 	pv.ObjectMeta = generateObjectMetaTemplate(pv.ObjectMeta, key, value, pv.Name)
 	pv.Spec = generatePersistentVolumeSpec(pv.Spec, key, value)
-	pvData, err := ylib.Marshal(pv)
+	tw, err := newTemplateWriter(pv)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	temp, err := tw.Render()
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
 	}
-	temp := removeEmptyFields(string(pvData))
-	return temp, valueFileGenerator{value: value}
+	return temp, valueFileGenerator{value: value}, nil
 }
 
-func horizontalPodAutoscaler(horizontalPodAutoscaler v1.HorizontalPodAutoscaler) (string, valueFileGenerator) {
+func horizontalPodAutoscaler(horizontalPodAutoscaler v1.HorizontalPodAutoscaler) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&horizontalPodAutoscaler.ObjectMeta)
 	cleanUpDecorators(horizontalPodAutoscaler.ObjectMeta.Annotations)
-	volumes := ""
 	value := make(map[string]interface{}, 0)
-	persistence := make(map[string]interface{}, 0)
-	key := generateSafeKey(horizontalPodAutoscaler.ObjectMeta.Name)
+	key := valueKey(horizontalPodAutoscaler.ObjectMeta.Name, "hpa")
 	horizontalPodAutoscaler.ObjectMeta = generateObjectMetaTemplate(horizontalPodAutoscaler.ObjectMeta, key, value, horizontalPodAutoscaler.ObjectMeta.Name)
 
-	template := ""
-	tempDeploymentByte, err := ylib.Marshal(horizontalPodAutoscaler)
+	tw, err := newTemplateWriter(horizontalPodAutoscaler)
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
 	}
-	tempDeployment := removeEmptyFields(string(tempDeploymentByte))
-
-	tempDeployment, value = generateTemplateForHorizontalPodAutoscaler(horizontalPodAutoscaler.Spec, tempDeployment, key, value)
 
-	if len(volumes) != 0 {
-		template = addVolumeToTemplate(tempDeployment, volumes)
-	} else {
-		template = tempDeployment
+	if err := generateTemplateForHorizontalPodAutoscaler(horizontalPodAutoscaler.Spec, tw, key, value); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	template, err := tw.Render()
+	if err != nil {
+		return "", valueFileGenerator{}, err
 	}
 
-	return template, valueFileGenerator{value: value, persistence: persistence}
+	return template, valueFileGenerator{value: value}, nil
 }
 
-func storageClassTemplate(storageClass storage.StorageClass) (string, valueFileGenerator) {
+func storageClassTemplate(storageClass storage.StorageClass) (string, valueFileGenerator, error) {
 	cleanUpObjectMeta(&storageClass.ObjectMeta)
 	value := make(map[string]interface{}, 0)
-	key := generateSafeKey(storageClass.ObjectMeta.Name)
+	key := valueKey(storageClass.ObjectMeta.Name, "storage")
 	storageClass.ObjectMeta = generateObjectMetaTemplate(storageClass.ObjectMeta, key, value, storageClass.ObjectMeta.Name)
 	value[Provisioner] = storageClass.Provisioner
 	storageClass.Provisioner = fmt.Sprintf("{{.Values.%s.%s}}", key, Provisioner)
 	storageClass.Parameters = mapToValueMaker(storageClass.Parameters, value, key)
-	storageData, err := ylib.Marshal(storageClass)
+	tw, err := newTemplateWriter(storageClass)
+	if err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	if err := setObjectMetaInTemplate(tw, key); err != nil {
+		return "", valueFileGenerator{}, err
+	}
+	storageData, err := tw.Render()
 	if err != nil {
-		log.Fatal(err)
+		return "", valueFileGenerator{}, err
 	}
-	return string(storageData), valueFileGenerator{value: value}
+	return storageData, valueFileGenerator{value: value}, nil
 }
 
-func addSecretData(secretData string, secretDataMap map[string]interface{}, key string) string {
-	elseCondition := "{{ else }}"
-	elseAction := "{{ randAlphaNum 10 | b64enc | quote }}"
-	end := "{{ end }}"
-	data := ""
-	for k, v := range secretDataMap {
-		if strings.HasPrefix(k, ".") {
-			// For values that starts with ".", the Values string get populated with ".." - error for helm
-			kmod := strings.Replace(k, ".", "", 1)
-			ifCondition := fmt.Sprintf("{{ if .Values.%s.%s }}", key, kmod)
-			data += fmt.Sprintf("  %s\n  %s: %s\n  %s\n  %s: %s\n  %s\n", ifCondition, k, v, elseCondition, k, elseAction, end)
-		} else {
-			ifCondition := fmt.Sprintf("{{ if .Values.%s.%s }}", key, k)
-			data += fmt.Sprintf("  %s\n  %s: %s\n  %s\n  %s: %s\n  %s\n", ifCondition, k, v, elseCondition, k, elseAction, end)
-		}
+// secretDataTemplate falls back to a random value when the secret data
+// is not set in values.
+func secretDataTemplate(key string, dataKey string) ifBlock {
+	return ifBlock{
+		Cond: fmt.Sprintf(".Values.%s.%s", key, dataKey),
+		Then: rawExpr(fmt.Sprintf("{{.Values.%s.%s}}", key, dataKey)),
+		Else: rawExpr("{{ randAlphaNum 10 | b64enc | quote }}"),
 	}
-	dataOfSecret := "data:" + "\n" + data
-	return secretData + dataOfSecret
 }
 
 func addPersistence(persistence map[string]interface{}, elements map[string]interface{}) map[string]interface{} {
@@ -708,13 +1181,85 @@ func addPersistence(persistence map[string]interface{}, elements map[string]inte
 	return persistence
 }
 
-func chartMetaData(name string) chart.Metadata {
-	return chart.Metadata{
-		Name:        name,
-		Description: "Helm chart generated by https://github.com/damarseta/chartify",
-		Version:     "0.1.0",
-		APIVersion:  "v1",
+// chartMetaData returns the Chart.yaml of the chart, g.Chart with defaults
+// for the fields it leaves empty.
+func (g Generator) chartMetaData() chart.Metadata {
+	md := g.Chart
+	md.Name = g.ChartName
+	md.APIVersion = chart.APIVersionV2
+	if len(md.Version) == 0 {
+		md.Version = "0.1.0"
+	}
+	if len(md.Description) == 0 {
+		md.Description = "Helm chart generated by https://github.com/damarseta/chartify"
+	}
+	if len(md.Type) == 0 {
+		md.Type = "application"
+	}
+	if len(md.AppVersion) == 0 && g.AppVersionFromImage {
+		md.AppVersion = primaryImageTag(g.YamlFiles)
+	}
+	return md
+}
+
+// Paths of the containers in the objects that have a pod spec.
+var containerPaths = [][]string{
+	{"spec", "containers"},
+	{"spec", "template", "spec", "containers"},
+	{"spec", "jobTemplate", "spec", "template", "spec", "containers"},
+}
+
+// primaryImageTag returns the image tag of the first container of the first
+// object with a pod spec, or "" if there is none.
+func primaryImageTag(objects []string) string {
+	for _, v := range objects {
+		obj, err := parseUnstructured(v)
+		if err != nil {
+			continue // reported when the object itself is generated
+		}
+		for _, path := range containerPaths {
+			containers, found, err := unstructured.NestedSlice(obj.Object, path...)
+			if err != nil || !found || len(containers) == 0 {
+				continue
+			}
+			container, ok := containers[0].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			image, _ := container["image"].(string)
+			return imageTag(image)
+		}
+	}
+	return ""
+}
+
+// imageTag returns the tag of an image reference, "latest" if it has none.
+func imageTag(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	indexSlash := strings.LastIndex(image, "/")
+	indexColon := strings.LastIndex(image, ":")
+	if indexColon > indexSlash {
+		return image[indexColon+1:]
+	}
+	return "latest"
+}
+
+// ParseMaintainer parses a maintainer given as "name" or "name <email>".
+func ParseMaintainer(s string) (*chart.Maintainer, error) {
+	m := &chart.Maintainer{Name: strings.TrimSpace(s)}
+	if i := strings.Index(s, "<"); i >= 0 {
+		if !strings.HasSuffix(strings.TrimSpace(s), ">") {
+			return nil, fmt.Errorf("maintainer %q: email must be enclosed in <>", s)
+		}
+		m.Name = strings.TrimSpace(s[:i])
+		m.Email = strings.TrimSuffix(strings.TrimSpace(s[i+1:]), ">")
 	}
+	if len(m.Name) == 0 {
+		return nil, fmt.Errorf("maintainer %q: name is required", s)
+	}
+	return m, nil
 }
 
 func mapToValueMaker(mp map[string]string, value map[string]interface{}, key string) map[string]string {
@@ -728,7 +1273,10 @@ func mapToValueMaker(mp map[string]string, value map[string]interface{}, key str
 func getInsideObjects(objects []string) map[string][]string {
 	obj := make(map[string][]string)
 	for _, v := range objects {
-		kind, name := getObjectKindAndName(v)
+		kind, name, err := getObjectKindAndName(v)
+		if err != nil {
+			continue // reported when the object itself is generated
+		}
 		for _, t := range chnageObjectType {
 			if kind == t {
 				obj[kind] = append(obj[kind], name)
@@ -738,60 +1286,31 @@ func getInsideObjects(objects []string) map[string][]string {
 	return obj
 }
 
-func getObjectKindAndName(yamlData string) (string, string) {
+func getObjectKindAndName(yamlData string) (string, string, error) {
 	kubeJson, err := yaml.ToJSON([]byte(yamlData))
 	if err != nil {
-		log.Fatal(err)
+		return "", "", err
 	}
 
 	m := make(map[string]interface{})
 	err = json.Unmarshal(kubeJson, &m)
 	if err != nil {
-		log.Println(err)
+		return "", "", err
 	}
-	objMeta, ok := m["metadata"].(map[string]interface{})
-	if !ok {
-		log.Fatal("Name not found")
-	}
-	name := objMeta["name"]
 	var typeMeta metav1.TypeMeta
-
 	err = json.Unmarshal(kubeJson, &typeMeta)
 	if err != nil {
-		log.Fatal(err)
+		return "", "", err
 	}
-	objName, ok := name.(string)
+	objMeta, ok := m["metadata"].(map[string]interface{})
 	if !ok {
-		return typeMeta.Kind, ""
+		return typeMeta.Kind, "", fmt.Errorf("metadata not found")
 	}
-	return typeMeta.Kind, objName
-}
-
-func modifyLabelSelector(selector *metav1.LabelSelector, templateLabels map[string]string, metaLabels map[string]string) {
-	if len(selector.MatchLabels) == 0 {
-		return
-	}
-	for k, v := range selector.MatchLabels {
-		_, ok := templateLabels[k]
-		if !ok {
-			continue
-		}
-		_, ok = metaLabels[k]
-		if !ok {
-			return
-		}
-		selector.MatchLabels[k] = "{{.Release.Name}}-" + v
-		templateLabels[k] = selector.MatchLabels[k]
-		metaLabels[k] = selector.MatchLabels[k]
-	}
-}
-
-func modifySvcLabelSelector(selector map[string]string) map[string]string {
-	for k, v := range selector {
-		selector[k] = "{{.Release.Name}}-" + v
+	objName, ok := objMeta["name"].(string)
+	if !ok {
+		return typeMeta.Kind, "", nil
 	}
-
-	return selector
+	return typeMeta.Kind, objName, nil
 }
 
 func cleanupForReplicaSets(rcSet *extensions.ReplicaSet) {
@@ -799,28 +1318,8 @@ func cleanupForReplicaSets(rcSet *extensions.ReplicaSet) {
 	cleanUpPodSpec(&rcSet.Spec.Template.Spec)
 	cleanUpDecorators(rcSet.ObjectMeta.Annotations)
 	cleanUpDecorators(rcSet.ObjectMeta.Labels)
-	cleanUpDecorators(rcSet.Spec.Selector.MatchLabels)
-	cleanUpDecorators(rcSet.Spec.Template.ObjectMeta.Labels)
-}
-
-func ReadLocalFiles(dirName string) []string {
-	var yamlFiles []string
-	files, err := ioutil.ReadDir(dirName)
-	if err != nil {
-		log.Fatal(err)
+	if rcSet.Spec.Selector != nil {
+		cleanUpDecorators(rcSet.Spec.Selector.MatchLabels)
 	}
-	for _, f := range files {
-		if filepath.Ext(f.Name()) != ".yml" && filepath.Ext(f.Name()) != ".yaml" {
-			fmt.Printf("skipping %s (%s) \n", f.Name(), filepath.Ext(f.Name()))
-			continue
-		}
-
-		fileDir := filepath.Join(dirName, f.Name())
-		dataByte, err := ioutil.ReadFile(fileDir)
-		if err != nil {
-			log.Fatal(err)
-		}
-		yamlFiles = append(yamlFiles, string(dataByte))
-	}
-	return yamlFiles
+	cleanUpDecorators(rcSet.Spec.Template.ObjectMeta.Labels)
 }
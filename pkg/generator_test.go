@@ -1,19 +1,28 @@
 package pkg
 
 import (
+	"bytes"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ghodss/yaml"
 	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
 	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/autoscaling/v1"
 	batch "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	networking "k8s.io/api/networking/v1"
 	storage "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func TestPodTemplate(t *testing.T) {
@@ -22,7 +31,8 @@ func TestPodTemplate(t *testing.T) {
 	pod := apiv1.Pod{}
 	err = yaml.Unmarshal(yamlFile, &pod)
 	assert.Nil(t, err)
-	template, values := podTemplate(pod)
+	template, values, err := podTemplate(pod)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/pod/output/pod_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), template)
@@ -35,7 +45,8 @@ func TestReplicationControllerTemplate(t *testing.T) {
 	rc := apiv1.ReplicationController{}
 	err = yaml.Unmarshal(yamlFile, &rc)
 	assert.Nil(t, err)
-	template, values := replicationControllerTemplate(rc)
+	template, values, err := replicationControllerTemplate(rc)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/rc/output/rc_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -48,7 +59,8 @@ func TestReplicaSetTemplate(t *testing.T) {
 	rcSet := extensions.ReplicaSet{}
 	err = yaml.Unmarshal(yamlFile, &rcSet)
 	assert.Nil(t, err)
-	template, values := replicaSetTemplate(rcSet)
+	template, values, err := replicaSetTemplate(rcSet)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/replicaset/output/replicaset_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -61,20 +73,42 @@ func TestJobTemplate(t *testing.T) {
 	job := batch.Job{}
 	err = yaml.Unmarshal(yamlFile, &job)
 	assert.Nil(t, err)
-	template, values := jobTemplate(job)
+	template, values, err := jobTemplate(job)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/job/output/job_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
 	valueChecker(t, "../testdata/job/output/job_value.yaml", values.value)
 }
 
+func TestCronJobTemplate(t *testing.T) {
+	yamlFile, err := ioutil.ReadFile("../testdata/cronjob/input/cronjob.yaml")
+	assert.Nil(t, err)
+	cronJob := batch.CronJob{}
+	err = yaml.Unmarshal(yamlFile, &cronJob)
+	assert.Nil(t, err)
+	template, values, err := cronJobTemplate(cronJob)
+	assert.Nil(t, err)
+	expectedTemplate, err := ioutil.ReadFile("../testdata/cronjob/output/cronjob_chart.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedTemplate), string(template))
+	valueChecker(t, "../testdata/cronjob/output/cronjob_value.yaml", values.value)
+
+	// the chart renders the hostPath volume from its persistence values
+	createTestChart(t, Generator{
+		YamlFiles: []string{string(yamlFile)},
+		Verify:    true,
+	})
+}
+
 func TestChartForConfigMap(t *testing.T) {
 	yamlFile, err := ioutil.ReadFile("../testdata/configmap/input/configmap.yaml")
 	assert.Nil(t, err)
 	configMap := apiv1.ConfigMap{}
 	err = yaml.Unmarshal(yamlFile, &configMap)
 	assert.Nil(t, err)
-	template, values := configMapTemplate(configMap)
+	template, values, err := configMapTemplate(configMap)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/configmap/output/configmap_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -87,7 +121,8 @@ func TestDaemonsetTemplate(t *testing.T) {
 	daemonset := extensions.DaemonSet{}
 	err = yaml.Unmarshal(yamlFile, &daemonset)
 	assert.Nil(t, err)
-	template, values := daemonsetTemplate(daemonset)
+	template, values, err := daemonsetTemplate(daemonset)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/daemon/output/daemon_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -100,7 +135,8 @@ func TestSecretTemplate(t *testing.T) {
 	secret := apiv1.Secret{}
 	err = yaml.Unmarshal(yamlFile, &secret)
 	assert.Nil(t, err)
-	template, values := secretTemplate(secret)
+	template, values, err := secretTemplate(secret)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/secret/output/secret_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -113,7 +149,8 @@ func TestPVTemplate(t *testing.T) {
 	pv := apiv1.PersistentVolume{}
 	err = yaml.Unmarshal(yamlFile, &pv)
 	assert.Nil(t, err)
-	template, values := pvTemplate(pv)
+	template, values, err := pvTemplate(pv)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/pv/output/pv_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -126,20 +163,85 @@ func TestServiceTemplate(t *testing.T) {
 	svc := apiv1.Service{}
 	err = yaml.Unmarshal(yamlFile, &svc)
 	assert.Nil(t, err)
-	template, values := serviceTemplate(svc)
+	template, values, err := serviceTemplate(svc)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/service/output/service_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
 	valueChecker(t, "../testdata/service/output/service_value.yaml", values.value)
 }
 
+func TestIngressTemplate(t *testing.T) {
+	yamlFile, err := ioutil.ReadFile("../testdata/ingress/input/ingress.yaml")
+	assert.Nil(t, err)
+	ing := networking.Ingress{}
+	err = yaml.Unmarshal(yamlFile, &ing)
+	assert.Nil(t, err)
+	ChartObject = map[string][]string{"Service": {"myapp"}}
+	defer func() {
+		ChartObject = nil
+	}()
+	template, values, err := ingressTemplate(ing)
+	assert.Nil(t, err)
+	expectedTemplate, err := ioutil.ReadFile("../testdata/ingress/output/ingress_chart.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedTemplate), string(template))
+	valueChecker(t, "../testdata/ingress/output/ingress_value.yaml", values.value)
+}
+
+func TestIngressHostsValues(t *testing.T) {
+	ingress, err := ioutil.ReadFile("../testdata/ingress/input/ingress.yaml")
+	assert.Nil(t, err)
+	service, err := ioutil.ReadFile("../testdata/service/input/service.yaml")
+	assert.Nil(t, err)
+	yamlFiles := []string{string(ingress), string(service)}
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+		Verify:    true,
+	})
+	c, err := loader.Load(chdir)
+	assert.Nil(t, err)
+	overrides := map[string]interface{}{
+		"myapp": map[string]interface{}{
+			"ingress": map[string]interface{}{
+				"hosts": []interface{}{
+					map[string]interface{}{"host": "a.example.com", "paths": []interface{}{
+						map[string]interface{}{"path": "/", "pathType": "Prefix", "backend": map[string]interface{}{
+							"service": map[string]interface{}{"name": "myapp", "port": map[string]interface{}{"number": 8765}},
+						}},
+					}},
+					map[string]interface{}{"host": "b.example.com", "paths": []interface{}{
+						map[string]interface{}{"path": "/", "pathType": "Prefix", "backend": map[string]interface{}{
+							"service": map[string]interface{}{"name": "external", "port": map[string]interface{}{"name": "http"}},
+						}},
+					}},
+				},
+				"tls": []interface{}{},
+			},
+		},
+	}
+	values, err := chartutil.ToRenderValues(c, overrides, verifyRelease, nil)
+	assert.Nil(t, err)
+	files, err := engine.Render(c, values)
+	assert.Nil(t, err)
+	rendered := networking.Ingress{}
+	assert.Nil(t, yaml.Unmarshal([]byte(files["test/templates/myapp.ingress.yaml"]), &rendered))
+	assert.Len(t, rendered.Spec.Rules, 2)
+	assert.Equal(t, "b.example.com", rendered.Spec.Rules[1].Host)
+	assert.Equal(t, "verify-test-myapp", rendered.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name)
+	assert.Equal(t, "external", rendered.Spec.Rules[1].HTTP.Paths[0].Backend.Service.Name)
+	assert.Empty(t, rendered.Spec.TLS)
+	assert.Contains(t, files["test/templates/NOTES.txt"], "  http://b.example.com/\n")
+}
+
 func TestPVCTemplate(t *testing.T) {
 	yamlFile, err := ioutil.ReadFile("../testdata/pvc/input/pvc.yaml")
 	assert.Nil(t, err)
 	pvc := apiv1.PersistentVolumeClaim{}
 	err = yaml.Unmarshal(yamlFile, &pvc)
 	assert.Nil(t, err)
-	template, values := pvcTemplate(pvc)
+	template, values, err := pvcTemplate(pvc)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/pvc/output/pvc_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -149,23 +251,53 @@ func TestPVCTemplate(t *testing.T) {
 func TestDeploymentTemplate(t *testing.T) {
 	yamlFile, err := ioutil.ReadFile("../testdata/deployment/input/deployment.yaml")
 	assert.Nil(t, err)
-	deployment := extensions.Deployment{}
+	deployment := apps.Deployment{}
 	err = yaml.Unmarshal(yamlFile, &deployment)
 	assert.Nil(t, err)
-	template, values := deploymentTemplate(deployment)
+	template, values, err := deploymentTemplate(deployment)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/deployment/output/deployment_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
 	valueChecker(t, "../testdata/deployment/output/deployment_value.yaml", values.value)
 }
 
+func TestProbesTemplate(t *testing.T) {
+	yamlFile, err := ioutil.ReadFile("../testdata/probes/input/deployment.yaml")
+	assert.Nil(t, err)
+	deployment := apps.Deployment{}
+	err = yaml.Unmarshal(yamlFile, &deployment)
+	assert.Nil(t, err)
+	template, values, err := deploymentTemplate(deployment)
+	assert.Nil(t, err)
+	expectedTemplate, err := ioutil.ReadFile("../testdata/probes/output/deployment_chart.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedTemplate), string(template))
+	valueChecker(t, "../testdata/probes/output/deployment_value.yaml", values.value)
+}
+
+func TestSecurityContextTemplate(t *testing.T) {
+	yamlFile, err := ioutil.ReadFile("../testdata/security_context/input/deployment.yaml")
+	assert.Nil(t, err)
+	deployment := apps.Deployment{}
+	err = yaml.Unmarshal(yamlFile, &deployment)
+	assert.Nil(t, err)
+	template, values, err := deploymentTemplate(deployment)
+	assert.Nil(t, err)
+	expectedTemplate, err := ioutil.ReadFile("../testdata/security_context/output/deployment_chart.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedTemplate), string(template))
+	valueChecker(t, "../testdata/security_context/output/deployment_value.yaml", values.value)
+}
+
 func TestStorageClassTemplate(t *testing.T) {
 	yamlFile, err := ioutil.ReadFile("../testdata/storageclass/input/storageclass.yaml")
 	assert.Nil(t, err)
 	storageclass := storage.StorageClass{}
 	err = yaml.Unmarshal(yamlFile, &storageclass)
 	assert.Nil(t, err)
-	template, values := storageClassTemplate(storageclass)
+	template, values, err := storageClassTemplate(storageclass)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/storageclass/output/storageclass_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -178,20 +310,36 @@ func TestStatefulsetTemplate(t *testing.T) {
 	statefulset := apps.StatefulSet{}
 	err = yaml.Unmarshal(yamlFile, &statefulset)
 	assert.Nil(t, err)
-	template, values := statefulsetTemplate(statefulset)
+	template, values, err := statefulsetTemplate(statefulset)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/statefulset/output/statefulset_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
 	valueChecker(t, "../testdata/statefulset/output/statefulset_value.yaml", values.value)
 }
 
+func TestSchedulingTemplate(t *testing.T) {
+	yamlFile, err := ioutil.ReadFile("../testdata/scheduling/input/statefulset.yaml")
+	assert.Nil(t, err)
+	statefulset := apps.StatefulSet{}
+	err = yaml.Unmarshal(yamlFile, &statefulset)
+	assert.Nil(t, err)
+	template, values, err := statefulsetTemplate(statefulset)
+	assert.Nil(t, err)
+	expectedTemplate, err := ioutil.ReadFile("../testdata/scheduling/output/statefulset_chart.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedTemplate), string(template))
+	valueChecker(t, "../testdata/scheduling/output/statefulset_value.yaml", values.value)
+}
+
 func TestServiceTemplateWithClusterIP(t *testing.T) {
 	yamlFile, err := ioutil.ReadFile("../testdata/service_clusterIP/input/service.yaml")
 	assert.Nil(t, err)
 	svc := apiv1.Service{}
 	err = yaml.Unmarshal(yamlFile, &svc)
 	assert.Nil(t, err)
-	template, values := serviceTemplate(svc)
+	template, values, err := serviceTemplate(svc)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/service_clusterIP/output/service_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -199,52 +347,638 @@ func TestServiceTemplateWithClusterIP(t *testing.T) {
 }
 
 func TestChartForVolume(t *testing.T) {
-	yamlFiles := ReadLocalFiles("../testdata/mix_objects/check_volume/input")
-	tmp, err := ioutil.TempDir(os.TempDir(), "test")
+	yamlFiles := readTestFiles(t, "../testdata/mix_objects/check_volume/input")
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+	})
+	files, err := ioutil.ReadDir("../testdata/mix_objects/check_volume/output")
+	assert.Nil(t, err)
+	for _, v := range files {
+		actualData, err := ioutil.ReadFile(filepath.Join(chdir, "templates", v.Name()))
+		assert.Nil(t, err)
+		expectedData, err := ioutil.ReadFile(filepath.Join("../testdata/mix_objects/check_volume/output", v.Name()))
+		assert.Equal(t, string(expectedData), string(actualData))
+	}
 	defer func() {
-		_ = os.Remove(tmp)
+		_ = os.Remove(chdir)
 	}()
+}
+
+func TestChartForRBAC(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/mix_objects/rbac/input")
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+	})
+	files, err := ioutil.ReadDir("../testdata/mix_objects/rbac/output")
+	assert.Nil(t, err)
+	for _, v := range files {
+		actualData, err := ioutil.ReadFile(filepath.Join(chdir, "templates", v.Name()))
+		assert.Nil(t, err)
+		expectedData, err := ioutil.ReadFile(filepath.Join("../testdata/mix_objects/rbac/output", v.Name()))
+		assert.Nil(t, err)
+		assert.Equal(t, string(expectedData), string(actualData))
+	}
+	actualValues, err := ioutil.ReadFile(filepath.Join(chdir, ValuesfileName))
+	assert.Nil(t, err)
+	expectedValues, err := ioutil.ReadFile("../testdata/mix_objects/rbac/value/values.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedValues), string(actualValues))
+
+	// objects can not take the values of the chart itself
+	configMap := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: rbac\ndata:\n  role: admin\n"
+	_, err = Generator{ChartName: "test", YamlFiles: append(yamlFiles, configMap), Location: t.TempDir()}.Create()
+	objErr, ok := err.(*ObjectError)
+	assert.True(t, ok)
+	assert.Equal(t, "ConfigMap", objErr.Kind)
+	assert.Equal(t, "rbac", objErr.Name)
+}
+
+func TestValuesSchema(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/mix_objects/values_schema/input")
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+	})
+	actualSchema, err := ioutil.ReadFile(filepath.Join(chdir, SchemafileName))
+	assert.Nil(t, err)
+	expectedSchema, err := ioutil.ReadFile("../testdata/mix_objects/values_schema/value/values.schema.json")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedSchema), string(actualSchema))
+
+	// overrides are validated merged with the defaults, they may give image
+	// tags as numbers but not unset the values templates need
+	c, err := loader.Load(chdir)
+	assert.Nil(t, err)
+	_, err = chartutil.ToRenderValues(c, map[string]interface{}{
+		"api": map[string]interface{}{"deployment": map[string]interface{}{
+			"api": map[string]interface{}{ImageTag: 2.1},
+		}},
+	}, verifyRelease, nil)
+	assert.Nil(t, err)
+	_, err = chartutil.ToRenderValues(c, map[string]interface{}{
+		"myapp": map[string]interface{}{"svc": map[string]interface{}{ServiceType: "Nodeport"}},
+	}, verifyRelease, nil)
+	assert.NotNil(t, err)
+	for _, override := range []map[string]interface{}{
+		{"api": map[string]interface{}{"deployment": map[string]interface{}{
+			"api": map[string]interface{}{ImageTag: nil},
+		}}},
+		{"api": map[string]interface{}{"deployment": map[string]interface{}{Replicas: nil}}},
+		{"myapp": map[string]interface{}{"svc": map[string]interface{}{ServiceType: nil}}},
+	} {
+		_, err = chartutil.ToRenderValues(c, override, verifyRelease, nil)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "is required")
+		}
+	}
+}
+
+func TestChartNotes(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/mix_objects/notes/input")
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+	})
+	actualNotes, err := ioutil.ReadFile(filepath.Join(chdir, TemplatesDir, NotesName))
+	assert.Nil(t, err)
+	expectedNotes, err := ioutil.ReadFile("../testdata/mix_objects/notes/output/NOTES.txt")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedNotes), string(actualNotes))
+
+	notes, err := notesTemplate(nil)
+	assert.Nil(t, err)
+	assert.Empty(t, notes)
+}
+
+func TestVerifyChart(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/mix_objects/rbac/input")
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+		Verify:    true,
+	})
+
+	valueFile := filepath.Join(chdir, ValuesfileName)
+	values, err := ioutil.ReadFile(valueFile)
+	assert.Nil(t, err)
+	values = []byte(strings.Replace(string(values), "restartPolicy: Always", "restartPolicy: Never", 1))
+	assert.Nil(t, ioutil.WriteFile(valueFile, values, 0o644))
+	err = VerifyChart(chdir, yamlFiles)
+	mismatches, ok := err.(Mismatches)
+	assert.True(t, ok)
+	assert.Len(t, mismatches, 1)
+	assert.Equal(t, "Pod", mismatches[0].Kind)
+	assert.Equal(t, "worker", mismatches[0].Name)
+	assert.Equal(t, "spec.restartPolicy", mismatches[0].Field)
+	assert.Equal(t, "Never", mismatches[0].Rendered)
+}
+
+func TestVerifySelector(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/mix_objects/selector/input")
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+	})
+
+	// the selector of the service keeps selecting the pods of the deployment,
+	// which has no labels of its own
+	assert.Nil(t, VerifyChart(chdir, yamlFiles))
+}
+
+func TestPruneSource(t *testing.T) {
+	deployment, err := parseUnstructured(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    app: web
+    pod-template-hash: 5d59d67564
+  name: web
+spec:
+  template:
+    spec:
+      nodeName: node-1
+      containers:
+      - name: web
+        terminationMessagePath: /dev/termination-log
+`)
+	assert.Nil(t, err)
+	cleanUpSource(deployment)
+	assert.Equal(t, map[string]interface{}{"app": "web"}, deployment.Object["metadata"].(map[string]interface{})["labels"])
+	assert.Equal(t, map[string]interface{}{
+		"template": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{map[string]interface{}{"name": "web"}},
+			},
+		},
+	}, deployment.Object["spec"])
+
+	// fields of other kinds are kept, even with the same name
+	node, err := parseUnstructured(`apiVersion: example.com/v1
+kind: Placement
+metadata:
+  name: web
+spec:
+  nodeName: node-1
+  clusterIP: 10.0.0.1
+`)
+	assert.Nil(t, err)
+	cleanUpSource(node)
+	assert.Equal(t, map[string]interface{}{"nodeName": "node-1", "clusterIP": "10.0.0.1"}, node.Object["spec"])
+}
+
+func TestLintChart(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/probes/input")
+	yamlFiles = append(yamlFiles, readTestFiles(t, "../testdata/statefulset/input")...)
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+		Lint:      true,
+	})
+
+	broken := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Values.missing.name }}\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(chdir, TemplatesDir, "broken.yaml"), []byte(broken), 0o644))
+	err := LintChart(chdir)
+	lintErr, ok := err.(LintError)
+	assert.True(t, ok)
+	assert.Contains(t, lintErr.Error(), "test/templates/broken.yaml:4:")
+}
+
+func TestPackageChart(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/probes/input")
+	repoDir := t.TempDir()
+	createTestChart(t, Generator{
+		YamlFiles:  yamlFiles,
+		Chart:      chart.Metadata{Version: "1.2.3"},
+		PackageDir: repoDir,
+		Index:      true,
+	})
+	_, err := os.Stat(filepath.Join(repoDir, "test-1.2.3.tgz"))
+	assert.Nil(t, err)
+	index, err := ioutil.ReadFile(filepath.Join(repoDir, IndexName))
+	assert.Nil(t, err)
+	assert.Contains(t, string(index), "- test-1.2.3.tgz")
+}
+
+func TestUpdateChart(t *testing.T) {
+	deployment, err := ioutil.ReadFile("../testdata/probes/input/deployment.yaml")
+	assert.Nil(t, err)
+	service, err := ioutil.ReadFile("../testdata/service/input/service.yaml")
+	assert.Nil(t, err)
+	tmp := t.TempDir()
+	g := Generator{
+		ChartName:    "test",
+		YamlFiles:    []string{string(deployment), string(service)},
+		Location:     tmp,
+		TrackChanges: true,
+	}
+	untracked := g
+	untracked.TrackChanges = false
+	untracked.Location = filepath.Join(tmp, "untracked")
+	chdir, err := untracked.Create()
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(chdir, GeneratedDir))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(chdir, HelmignoreName))
+	assert.True(t, os.IsNotExist(err))
+
+	chdir, err = g.Create()
+	assert.Nil(t, err)
+
+	// edit the chart
+	valueFile := filepath.Join(chdir, ValuesfileName)
+	values, err := ioutil.ReadFile(valueFile)
+	assert.Nil(t, err)
+	values = []byte(strings.Replace(string(values), "replicas: 2", "replicas: 5", 1))
+	assert.Nil(t, ioutil.WriteFile(valueFile, values, 0o644))
+	svcTemplate := filepath.Join(chdir, TemplatesDir, "myapp.svc.yaml")
+	svc, err := ioutil.ReadFile(svcTemplate)
+	assert.Nil(t, err)
+	editedSvc := append(svc, "# edited\n"...)
+	assert.Nil(t, ioutil.WriteFile(svcTemplate, editedSvc, 0o644))
+
+	// change the objects
+	newDeployment := strings.Replace(string(deployment), "example/api:2.1.0", "example/api:2.2.0", 1)
+	newDeployment = newDeployment[:strings.Index(newDeployment, "      - image: example/log-shipper")] + "      restartPolicy: Always\n"
+	g.YamlFiles = []string{newDeployment, strings.Replace(string(service), "port: 8765", "port: 8766", 1)}
+	_, err = g.Update()
+	assert.Nil(t, err)
+
+	merged, err := readValues(valueFile)
+	assert.Nil(t, err)
+	api := merged["api"].(map[string]interface{})["deployment"].(map[string]interface{})
+	assert.Equal(t, float64(5), api["replicas"])
+	assert.Equal(t, "2.2.0", api["api"].(map[string]interface{})[ImageTag])
+	assert.Contains(t, api, "logshipper")
+
+	deploymentTemplate, err := ioutil.ReadFile(filepath.Join(chdir, TemplatesDir, "api.deployment.yaml"))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(deploymentTemplate), "logshipper")
+	svc, err = ioutil.ReadFile(svcTemplate)
+	assert.Nil(t, err)
+	assert.Equal(t, string(editedSvc), string(svc))
+	conflict, err := ioutil.ReadFile(svcTemplate + ConflictSuffix)
+	assert.Nil(t, err)
+	assert.Contains(t, string(conflict), "port: 8766")
+	base, err := ioutil.ReadFile(filepath.Join(chdir, GeneratedDir, TemplatesDir, "myapp.svc.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, string(conflict), string(base))
+}
+
+func TestChartOutput(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/probes/input")
+	out := &MemoryWriter{}
+	g := Generator{
+		ChartName: "test",
+		YamlFiles: yamlFiles,
+		Location:  t.TempDir(),
+		Output:    out,
+	}
+	chdir, err := g.Create()
+	assert.Nil(t, err)
+	_, err = os.Stat(chdir)
+	assert.True(t, os.IsNotExist(err))
+	c, err := out.Chart()
+	assert.Nil(t, err)
+	assert.Equal(t, "test", c.Name())
+	assert.Contains(t, c.Values, "api")
+	var templates []string
+	for _, f := range c.Templates {
+		templates = append(templates, f.Name)
+	}
+	assert.Contains(t, templates, "templates/api.deployment.yaml")
+	assert.Contains(t, templates, "templates/"+HelpersName)
+
+	var stream bytes.Buffer
+	g.Output = StreamWriter{W: &stream}
+	_, err = g.Create()
+	assert.Nil(t, err)
+	assert.Contains(t, stream.String(), "---\n# Source: templates/api.deployment.yaml\napiVersion: apps/v1\n")
+
+	g.Lint = true
+	_, err = g.Create()
+	assert.NotNil(t, err)
+}
+
+func TestGenericTemplateEscaping(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/mix_objects/prometheus_rule/input")
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+		Verify:    true,
+	})
+	actualData, err := ioutil.ReadFile(filepath.Join(chdir, TemplatesDir, "node-alerts.prometheusrule.yaml"))
+	assert.Nil(t, err)
+	expectedData, err := ioutil.ReadFile("../testdata/mix_objects/prometheus_rule/output/node-alerts.prometheusrule.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedData), string(actualData))
+}
+
+func TestChartForCRD(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/mix_objects/crd/input")
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+	})
+	actualData, err := ioutil.ReadFile(filepath.Join(chdir, CRDsDir, "crontabs.stable.example.com.yaml"))
 	assert.Nil(t, err)
+	expectedData, err := ioutil.ReadFile("../testdata/mix_objects/crd/output/crontabs.stable.example.com.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedData), string(actualData))
+	_, err = os.Stat(filepath.Join(chdir, TemplatesDir, "crontabs.stable.example.com.yaml"))
+	assert.True(t, os.IsNotExist(err))
+	actualData, err = ioutil.ReadFile(filepath.Join(chdir, TemplatesDir, "nightly.crontab.yaml"))
+	assert.Nil(t, err)
+	expectedData, err = ioutil.ReadFile("../testdata/mix_objects/crd/output/nightly.crontab.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expectedData), string(actualData))
+	assert.Equal(t, []string{`CronTab "nightly" depends on CustomResourceDefinition "crontabs.stable.example.com"`}, crdDependents(yamlFiles))
+}
+
+type cronTabHandler struct{}
+
+func (cronTabHandler) Match(gvk schema.GroupVersionKind) bool {
+	return gvk.Group == "stable.example.com" && gvk.Kind == "CronTab"
+}
+
+func (cronTabHandler) Generate(kubeJson []byte) (*Template, error) {
+	return &Template{
+		Name:    "nightly",
+		Suffix:  "crontab",
+		Content: "kind: CronTab\n",
+		Values:  map[string]interface{}{"cronSpec": "0 0 * * *"},
+	}, nil
+}
+
+func TestRegisterHandler(t *testing.T) {
+	yamlFiles, _, err := ReadLocalFiles("../testdata/mix_objects/crd/input", FileFilter{})
+	assert.Nil(t, err)
+	tmp, err := ioutil.TempDir(os.TempDir(), "test")
+	assert.Nil(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
 	g := Generator{
 		ChartName: "test",
 		YamlFiles: yamlFiles,
 		Location:  tmp,
 	}
+	g.RegisterHandler(cronTabHandler{})
 	chdir, err := g.Create()
 	assert.Nil(t, err)
-	files, err := ioutil.ReadDir("../testdata/mix_objects/check_volume/output")
+	actualData, err := ioutil.ReadFile(filepath.Join(chdir, TemplatesDir, "nightly.crontab.yaml"))
 	assert.Nil(t, err)
-	for _, v := range files {
-		acturalData, err := ioutil.ReadFile(filepath.Join(chdir, "templates", v.Name()))
+	assert.Equal(t, "kind: CronTab\n", string(actualData))
+	actualValues, err := ioutil.ReadFile(filepath.Join(chdir, ValuesfileName))
+	assert.Nil(t, err)
+	assert.Equal(t, "commonAnnotations: {}\ncommonLabels: {}\nfullnameOverride: \"\"\nnameOverride: \"\"\nnightly:\n  crontab:\n    cronSpec: 0 0 * * *\n", string(actualValues))
+}
+
+func TestHelperNames(t *testing.T) {
+	yamlFiles, _, err := ReadLocalFiles("../testdata/mix_objects/same_name/input", FileFilter{})
+	assert.Nil(t, err)
+	tmp, err := ioutil.TempDir(os.TempDir(), "test")
+	assert.Nil(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+	for _, name := range []string{"frontend", "backend"} {
+		g := Generator{
+			ChartName: name,
+			YamlFiles: yamlFiles,
+			Location:  tmp,
+		}
+		chdir, err := g.Create()
 		assert.Nil(t, err)
-		expectedData, err := ioutil.ReadFile(filepath.Join("../testdata/mix_objects/check_volume/output", v.Name()))
-		assert.Equal(t, string(expectedData), string(acturalData))
+		svc, err := ioutil.ReadFile(filepath.Join(chdir, TemplatesDir, "web.svc.yaml"))
+		assert.Nil(t, err)
+		assert.Contains(t, string(svc), `include "`+name+`.labels"`)
+		assert.NotContains(t, string(svc), chartNamePlaceholder)
+		helpers, err := ioutil.ReadFile(filepath.Join(chdir, TemplatesDir, HelpersName))
+		assert.Nil(t, err)
+		assert.Contains(t, string(helpers), `define "`+name+`.labels"`)
+	}
+}
+
+func TestBuiltinHandlerGroups(t *testing.T) {
+	g := Generator{}
+	assert.NotNil(t, g.handlerFor(apiv1.SchemeGroupVersion.WithKind("Service")))
+	assert.NotNil(t, g.handlerFor(extensions.SchemeGroupVersion.WithKind("Deployment")))
+	assert.Nil(t, g.handlerFor(schema.GroupVersionKind{Group: "serving.knative.dev", Version: "v1", Kind: "Service"}))
+	assert.Nil(t, g.handlerFor(schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Job"}))
+	assert.NotNil(t, g.handlerFor(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: CRDKind}))
+}
+
+func TestSameNameValues(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/mix_objects/same_name/input")
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+	})
+	values, err := readValues(filepath.Join(chdir, ValuesfileName))
+	assert.Nil(t, err)
+	web := values["web"].(map[string]interface{})
+	annotations := func(suffix string) interface{} {
+		return web[suffix].(map[string]interface{})[Annotations]
 	}
+	assert.Equal(t, map[string]interface{}{"team": "frontend"}, annotations("deployment"))
+	assert.Equal(t, map[string]interface{}{"prometheus.io/scrape": "true"}, annotations("svc"))
+	assert.Equal(t, map[string]interface{}{"cert-manager.io/cluster-issuer": "letsencrypt"}, annotations("ingress"))
+	svc, err := ioutil.ReadFile(filepath.Join(chdir, TemplatesDir, "web.svc.yaml"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(svc), ".Values.web.svc.annotations")
+}
+
+func TestReadLocalFilesMultiDocument(t *testing.T) {
+	yamlFiles, sources, err := ReadLocalFiles("../testdata/multi_document/input", FileFilter{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"../testdata/multi_document/input/list.yaml[0].items[0]",
+		"../testdata/multi_document/input/list.yaml[0].items[1]",
+		"../testdata/multi_document/input/list.yaml[1].items[0]",
+		"../testdata/multi_document/input/stream.yaml[0]",
+		"../testdata/multi_document/input/stream.yaml[1]",
+	}, sources)
+	var objects []string
+	for _, v := range yamlFiles {
+		kind, name, err := getObjectKindAndName(v)
+		assert.Nil(t, err)
+		objects = append(objects, kind+"/"+name)
+	}
+	assert.Equal(t, []string{"Secret/app-secret", "ConfigMap/app-extra", "Deployment/app", "ConfigMap/app-config", "Service/app"}, objects)
+}
+
+func TestReadLocalFilesRecursive(t *testing.T) {
+	var log bytes.Buffer
+	LogOutput = &log
 	defer func() {
-		_ = os.Remove(chdir)
+		LogOutput = os.Stdout
+	}()
+	dir := "../testdata/nested_dirs/input"
+	_, sources, err := ReadLocalFiles(dir, FileFilter{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "db/configmap.yaml"),
+		filepath.Join(dir, "web/deployment.yaml"),
+		filepath.Join(dir, "web/service.json"),
+	}, sources)
+	assert.Equal(t, "skipping README.md (.md)\nskipping "+filepath.Join(dir, "ci/workflow.yml")+" (not a Kubernetes object)\n", log.String())
+	log.Reset()
+
+	_, sources, err = ReadLocalFiles(dir, FileFilter{Exclude: []string{"db"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "web/deployment.yaml"),
+		filepath.Join(dir, "web/service.json"),
+	}, sources)
+	log.Reset()
+
+	_, sources, err = ReadLocalFiles(dir, FileFilter{Include: []string{"*.json", "db/*"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "db/configmap.yaml"),
+		filepath.Join(dir, "web/service.json"),
+	}, sources)
+	// files left out by the filter are not reported as skipped
+	assert.Empty(t, log.String())
+}
+
+func TestReadFiles(t *testing.T) {
+	stdin := strings.NewReader("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: piped\n")
+	names := []string{"-", "../testdata/pod/input/pod.yaml", "../testdata/nested_dirs/input/db"}
+	yamlFiles, sources, err := ReadFiles(names, FileFilter{}, stdin)
+	assert.Nil(t, err)
+	assert.Len(t, yamlFiles, 3)
+	assert.Equal(t, []string{
+		"<stdin>",
+		"../testdata/pod/input/pod.yaml",
+		filepath.Join("../testdata/nested_dirs/input/db", "configmap.yaml"),
+	}, sources)
+
+	_, _, err = ReadFiles([]string{"-", "-"}, FileFilter{}, strings.NewReader(""))
+	assert.NotNil(t, err)
+}
+
+func TestCreateObjectError(t *testing.T) {
+	badDeployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: broken
+spec:
+  replicas: two
+`
+	pod, err := ioutil.ReadFile("../testdata/pod/input/pod.yaml")
+	assert.Nil(t, err)
+	tmp, err := ioutil.TempDir(os.TempDir(), "test")
+	assert.Nil(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+	g := Generator{
+		ChartName:   "test",
+		YamlFiles:   []string{badDeployment, string(pod)},
+		YamlSources: []string{"deployment.yaml", "pod.yaml"},
+		Location:    tmp,
+	}
+	_, err = g.Create()
+	objErr, ok := err.(*ObjectError)
+	assert.True(t, ok)
+	assert.Equal(t, "Deployment", objErr.Kind)
+	assert.Equal(t, "broken", objErr.Name)
+	assert.Equal(t, "deployment.yaml", objErr.Source)
+
+	noSelector := "apiVersion: apps/v1\nkind: ReplicaSet\nmetadata:\n  name: noselector\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n        image: app:1.0\n"
+	g.YamlFiles[0] = noSelector
+	g.YamlSources[0] = "replicaset.yaml"
+	_, err = g.Create()
+	objErr, ok = err.(*ObjectError)
+	assert.True(t, ok)
+	assert.Equal(t, "ReplicaSet", objErr.Kind)
+	assert.Equal(t, "noselector", objErr.Name)
+	assert.Equal(t, "replicaset.yaml", objErr.Source)
+	assert.True(t, errors.Is(err, ErrMissingSelector))
+
+	g.YamlFiles[0] = badDeployment
+	g.YamlSources[0] = "deployment.yaml"
+	g.ContinueOnError = true
+	chdir, err := g.Create()
+	skipped, ok := err.(ObjectErrors)
+	assert.True(t, ok)
+	assert.Len(t, skipped, 1)
+	_, err = os.Stat(filepath.Join(chdir, "templates", "mypod.pod.yaml"))
+	assert.Nil(t, err)
+}
+
+func TestChartMetadata(t *testing.T) {
+	deployment, err := ioutil.ReadFile("../testdata/probes/input/deployment.yaml")
+	assert.Nil(t, err)
+	tmp, err := ioutil.TempDir(os.TempDir(), "test")
+	assert.Nil(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmp)
 	}()
+	maintainer, err := ParseMaintainer("Jane Doe <jane@example.com>")
+	assert.Nil(t, err)
+	g := Generator{
+		ChartName: "test",
+		YamlFiles: []string{string(deployment)},
+		Location:  tmp,
+		Chart: chart.Metadata{
+			Version:     "1.2.3",
+			Keywords:    []string{"api"},
+			Maintainers: []*chart.Maintainer{maintainer},
+		},
+		AppVersionFromImage: true,
+	}
+	chdir, err := g.Create()
+	assert.Nil(t, err)
+	chartfile, err := ioutil.ReadFile(filepath.Join(chdir, ChartfileName))
+	assert.Nil(t, err)
+	md := chart.Metadata{}
+	assert.Nil(t, yaml.Unmarshal(chartfile, &md))
+	assert.Equal(t, chart.APIVersionV2, md.APIVersion)
+	assert.Equal(t, "test", md.Name)
+	assert.Equal(t, "1.2.3", md.Version)
+	assert.Equal(t, "2.1.0", md.AppVersion)
+	assert.Equal(t, "application", md.Type)
+	assert.Equal(t, []string{"api"}, md.Keywords)
+	assert.Equal(t, "jane@example.com", md.Maintainers[0].Email)
+
+	g.Chart.Type = "plugin"
+	_, err = g.Create()
+	assert.NotNil(t, err)
+
+	_, err = ParseMaintainer("Jane Doe <jane@example.com")
+	assert.NotNil(t, err)
 }
 
 func TestChartForMultipleContainer(t *testing.T) {
 	yamlFile, err := ioutil.ReadFile("../testdata/multiple_container/input/deployment.yaml")
 	assert.Nil(t, err)
-	deployment := extensions.Deployment{}
+	deployment := apps.Deployment{}
 	err = yaml.Unmarshal(yamlFile, &deployment)
 	assert.Nil(t, err)
-	template, values := deploymentTemplate(deployment)
+	template, values, err := deploymentTemplate(deployment)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/multiple_container/output/deployment_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
 	valueChecker(t, "../testdata/multiple_container/output/deployment_value.yaml", values.value)
 }
 
+func TestContainerEnvValues(t *testing.T) {
+	yamlFiles := readTestFiles(t, "../testdata/mix_objects/env/input")
+	chdir := createTestChart(t, Generator{
+		YamlFiles: yamlFiles,
+		Verify:    true,
+	})
+	values, err := readValues(filepath.Join(chdir, ValuesfileName))
+	assert.Nil(t, err)
+	container := values["worker"].(map[string]interface{})["deployment"].(map[string]interface{})["worker"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"resources": "/srv/resources", "image": "thumbnail"}, container[Env])
+	assert.Equal(t, "example/worker", container[Image])
+	assert.Equal(t, map[string]interface{}{"limits": map[string]interface{}{"memory": "128Mi"}}, container[Resources])
+}
+
 func TestDeploymentSecretsTemplate(t *testing.T) {
 	yamlFile, err := ioutil.ReadFile("../testdata/deployment_pullsecret/input/deployment.yaml")
 	assert.Nil(t, err)
-	deployment := extensions.Deployment{}
+	deployment := apps.Deployment{}
 	err = yaml.Unmarshal(yamlFile, &deployment)
 	assert.Nil(t, err)
-	template, values := deploymentTemplate(deployment)
+	template, values, err := deploymentTemplate(deployment)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/deployment_pullsecret/output/deployment_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -255,7 +989,8 @@ func TestDeploymentSecretsTemplate(t *testing.T) {
 	secret := apiv1.Secret{}
 	err = yaml.Unmarshal(secretyamlFile, &secret)
 	assert.Nil(t, err)
-	secrettemplate, secretvalues := secretTemplate(secret)
+	secrettemplate, secretvalues, err := secretTemplate(secret)
+	assert.Nil(t, err)
 	secretexpectedTemplate, err := ioutil.ReadFile("../testdata/deployment_pullsecret/output/secret_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(secretexpectedTemplate), string(secrettemplate))
@@ -268,7 +1003,8 @@ func TestHorizontalPodAutoscalerTemplate(t *testing.T) {
 	hpa := v1.HorizontalPodAutoscaler{}
 	err = yaml.Unmarshal(yamlFile, &hpa)
 	assert.Nil(t, err)
-	template, values := horizontalPodAutoscaler(hpa)
+	template, values, err := horizontalPodAutoscaler(hpa)
+	assert.Nil(t, err)
 	expectedTemplate, err := ioutil.ReadFile("../testdata/hpa/output/hpa_chart.yaml")
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedTemplate), string(template))
@@ -282,3 +1018,24 @@ func valueChecker(t *testing.T, expectedPath string, value map[string]interface{
 	assert.Nil(t, err)
 	assert.Equal(t, string(expectedValues), string(valuesInfo))
 }
+
+// createTestChart creates the chart of g, named test unless g names it, in a
+// temporary directory removed after the test, and returns the chart directory.
+func createTestChart(t *testing.T, g Generator) string {
+	t.Helper()
+	if len(g.ChartName) == 0 {
+		g.ChartName = "test"
+	}
+	g.Location = t.TempDir()
+	chdir, err := g.Create()
+	assert.Nil(t, err)
+	return chdir
+}
+
+// readTestFiles reads the Kubernetes objects of a test case directory.
+func readTestFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	yamlFiles, _, err := ReadLocalFiles(dir, FileFilter{})
+	assert.Nil(t, err)
+	return yamlFiles
+}
@@ -0,0 +1,267 @@
+package pkg
+
+import (
+	"encoding/json"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/autoscaling/v1"
+	batch "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	extensions "k8s.io/api/extensions/v1beta1"
+	networking "k8s.io/api/networking/v1"
+	rbac "k8s.io/api/rbac/v1"
+	storage "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KindHandler turns Kubernetes objects of the kinds it matches into chart
+// templates. Handlers registered on a Generator are tried in order before
+// the built-in ones, so they can add kinds or replace how a built-in kind
+// is generated.
+type KindHandler interface {
+	// Match reports whether the handler generates templates for gvk.
+	Match(gvk schema.GroupVersionKind) bool
+	// Generate returns the template for an object, given as JSON.
+	Generate(kubeJson []byte) (*Template, error)
+}
+
+// Template is the chart template generated for a single object.
+type Template struct {
+	// Name is the object name. The template is written to
+	// <Dir>/<Name>.<Suffix>.yaml and Values is merged into values.yaml
+	// under the safe keys of Name and Suffix.
+	Name   string
+	Suffix string
+	// Dir is the chart directory the template is written to, templates/
+	// when empty.
+	Dir string
+	// Content is the template itself. <CHARTNAME> in it stands for the name
+	// of the chart, as in the names of its helper templates.
+	Content string
+	// Values are referenced by Content as .Values.<name>.<suffix>.
+	Values map[string]interface{}
+	// Persistence is merged into the persistence section of values.yaml.
+	Persistence map[string]interface{}
+}
+
+// RegisterHandler adds a handler that is tried before the built-in ones.
+func (g *Generator) RegisterHandler(h KindHandler) {
+	g.Handlers = append(g.Handlers, h)
+}
+
+// handlerFor returns the handler for gvk, or nil if there is none.
+func (g Generator) handlerFor(gvk schema.GroupVersionKind) KindHandler {
+	for _, h := range g.Handlers {
+		if h.Match(gvk) {
+			return h
+		}
+	}
+	for _, h := range builtinHandlers {
+		if h.Match(gvk) {
+			return h
+		}
+	}
+	return nil
+}
+
+type builtinHandler struct {
+	kind        string
+	apiVersions []string
+	suffix      string
+	dir         string
+	generate    func(kubeJson []byte) (string, valueFileGenerator, error)
+}
+
+func (h builtinHandler) Match(gvk schema.GroupVersionKind) bool {
+	if gvk.Kind != h.kind {
+		return false
+	}
+	for _, v := range h.apiVersions {
+		if gvk.GroupVersion().String() == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Kinds of the same name in other groups, such as Knative Services, are left
+// to the generic template.
+var (
+	coreVersions     = []string{apiv1.SchemeGroupVersion.String()}
+	workloadVersions = []string{appsv1.SchemeGroupVersion.String(), "apps/v1beta2", "apps/v1beta1", extensions.SchemeGroupVersion.String()}
+	// StatefulSets were never served from extensions
+	statefulSetVersions = []string{appsv1.SchemeGroupVersion.String(), "apps/v1beta2", "apps/v1beta1", "apps/v1alpha1"}
+)
+
+func (h builtinHandler) Generate(kubeJson []byte) (*Template, error) {
+	var meta metav1.PartialObjectMetadata
+	if err := json.Unmarshal(kubeJson, &meta); err != nil {
+		return nil, err
+	}
+	template, values, err := h.generate(kubeJson)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{
+		Name:        meta.Name,
+		Suffix:      h.suffix,
+		Dir:         h.dir,
+		Content:     template,
+		Values:      values.value,
+		Persistence: values.persistence,
+	}, nil
+}
+
+var builtinHandlers = []builtinHandler{
+	// CustomResourceDefinitions are copied to crds/ as they are, Helm does
+	// not template them
+	{kind: CRDKind, apiVersions: []string{"apiextensions.k8s.io/v1", "apiextensions.k8s.io/v1beta1"}, dir: CRDsDir, generate: crdTemplate},
+	{kind: "Pod", apiVersions: coreVersions, suffix: "pod", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		pod := apiv1.Pod{}
+		if err := json.Unmarshal(kubeJson, &pod); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return podTemplate(pod)
+	}},
+	{kind: "ReplicationController", apiVersions: coreVersions, suffix: "rc", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		rc := apiv1.ReplicationController{}
+		if err := json.Unmarshal(kubeJson, &rc); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return replicationControllerTemplate(rc)
+	}},
+	{kind: "Deployment", apiVersions: workloadVersions, suffix: "deployment", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		deployment := appsv1.Deployment{}
+		if err := json.Unmarshal(kubeJson, &deployment); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return deploymentTemplate(deployment)
+	}},
+	{kind: "Job", apiVersions: []string{batch.SchemeGroupVersion.String()}, suffix: "job", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		job := batch.Job{}
+		if err := json.Unmarshal(kubeJson, &job); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return jobTemplate(job)
+	}},
+	{kind: "CronJob", apiVersions: []string{batch.SchemeGroupVersion.String()}, suffix: "cronjob", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		cronJob := batch.CronJob{}
+		if err := json.Unmarshal(kubeJson, &cronJob); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return cronJobTemplate(cronJob)
+	}},
+	{kind: "DaemonSet", apiVersions: workloadVersions, suffix: "daemonset", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		daemonset := extensions.DaemonSet{}
+		if err := json.Unmarshal(kubeJson, &daemonset); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return daemonsetTemplate(daemonset)
+	}},
+	{kind: "ReplicaSet", apiVersions: workloadVersions, suffix: "rs", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		rcSet := extensions.ReplicaSet{}
+		if err := json.Unmarshal(kubeJson, &rcSet); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return replicaSetTemplate(rcSet)
+	}},
+	{kind: "StatefulSet", apiVersions: statefulSetVersions, suffix: "statefulset", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		statefulset := appsv1.StatefulSet{}
+		if err := json.Unmarshal(kubeJson, &statefulset); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return statefulsetTemplate(statefulset)
+	}},
+	{kind: "Service", apiVersions: coreVersions, suffix: "svc", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		service := apiv1.Service{}
+		if err := json.Unmarshal(kubeJson, &service); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return serviceTemplate(service)
+	}},
+	{kind: "Ingress", apiVersions: []string{networking.SchemeGroupVersion.String()}, suffix: "ingress", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		ingress := networking.Ingress{}
+		if err := json.Unmarshal(kubeJson, &ingress); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return ingressTemplate(ingress)
+	}},
+	{kind: "ServiceAccount", apiVersions: coreVersions, suffix: "serviceaccount", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		serviceAccount := apiv1.ServiceAccount{}
+		if err := json.Unmarshal(kubeJson, &serviceAccount); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return serviceAccountTemplate(serviceAccount)
+	}},
+	{kind: "Role", apiVersions: []string{rbac.SchemeGroupVersion.String()}, suffix: "role", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		role := rbac.Role{}
+		if err := json.Unmarshal(kubeJson, &role); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return roleTemplate(role)
+	}},
+	{kind: "RoleBinding", apiVersions: []string{rbac.SchemeGroupVersion.String()}, suffix: "rolebinding", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		roleBinding := rbac.RoleBinding{}
+		if err := json.Unmarshal(kubeJson, &roleBinding); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return roleBindingTemplate(roleBinding)
+	}},
+	{kind: "ClusterRole", apiVersions: []string{rbac.SchemeGroupVersion.String()}, suffix: "clusterrole", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		clusterRole := rbac.ClusterRole{}
+		if err := json.Unmarshal(kubeJson, &clusterRole); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return clusterRoleTemplate(clusterRole)
+	}},
+	{kind: "ClusterRoleBinding", apiVersions: []string{rbac.SchemeGroupVersion.String()}, suffix: "clusterrolebinding", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		clusterRoleBinding := rbac.ClusterRoleBinding{}
+		if err := json.Unmarshal(kubeJson, &clusterRoleBinding); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return clusterRoleBindingTemplate(clusterRoleBinding)
+	}},
+	{kind: "ConfigMap", apiVersions: coreVersions, suffix: "configmap", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		configMap := apiv1.ConfigMap{}
+		if err := json.Unmarshal(kubeJson, &configMap); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return configMapTemplate(configMap)
+	}},
+	{kind: "Secret", apiVersions: coreVersions, suffix: "secret", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		secret := apiv1.Secret{}
+		if err := json.Unmarshal(kubeJson, &secret); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return secretTemplate(secret)
+	}},
+	{kind: "PersistentVolumeClaim", apiVersions: coreVersions, suffix: "pvc", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		pvc := apiv1.PersistentVolumeClaim{}
+		if err := json.Unmarshal(kubeJson, &pvc); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return pvcTemplate(pvc)
+	}},
+	{kind: "PersistentVolume", apiVersions: coreVersions, suffix: "pv", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		pv := apiv1.PersistentVolume{}
+		if err := json.Unmarshal(kubeJson, &pv); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return pvTemplate(pv)
+	}},
+	{kind: "StorageClass", apiVersions: []string{storage.SchemeGroupVersion.String(), "storage.k8s.io/v1beta1"}, suffix: "storage", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		storageClass := storage.StorageClass{}
+		if err := json.Unmarshal(kubeJson, &storageClass); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return storageClassTemplate(storageClass)
+	}},
+	{kind: "HorizontalPodAutoscaler", apiVersions: []string{v1.SchemeGroupVersion.String()}, suffix: "hpa", generate: func(kubeJson []byte) (string, valueFileGenerator, error) {
+		podAutoscaler := v1.HorizontalPodAutoscaler{}
+		if err := json.Unmarshal(kubeJson, &podAutoscaler); err != nil {
+			return "", valueFileGenerator{}, err
+		}
+		return horizontalPodAutoscaler(podAutoscaler)
+	}},
+}
@@ -3,7 +3,6 @@ package pkg
 import (
 	"context"
 	"fmt"
-	"log"
 	"reflect"
 	"strings"
 
@@ -29,6 +28,7 @@ import (
 
 type KubeObjects struct {
 	ConfigMaps               []string
+	CronJobs                 []string
 	Deployments              []string
 	Daemons                  []string
 	Jobs                     []string
@@ -44,13 +44,12 @@ type KubeObjects struct {
 	HorizontalPodAutoscalers []string
 }
 
-func (ko KubeObjects) Extract() []string {
+func (ko KubeObjects) Extract() ([]string, error) {
 	kubeClient, err := newKubeClient()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	yamlFiles := ko.readKubernetesObjects(kubeClient)
-	return yamlFiles
+	return ko.readKubernetesObjects(kubeClient)
 }
 
 func (ko KubeObjects) CheckFlags() bool {
@@ -63,64 +62,55 @@ func (ko KubeObjects) CheckFlags() bool {
 	return false
 }
 
-func (ko KubeObjects) readKubernetesObjects(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) readKubernetesObjects(kubeClient clientset.Interface) ([]string, error) {
 	var yamlFiles []string
-	if len(ko.Pods) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getPods(kubeClient))
-	}
-	if len(ko.Services) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getServices(kubeClient))
-	}
-	if len(ko.ReplicationControllers) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getReplicationControllers(kubeClient))
-	}
-	if len(ko.Secrets) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getSecrets(kubeClient))
-	}
-	if len(ko.ConfigMaps) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getConfigMaps(kubeClient))
-	}
-	if len(ko.StatefulSets) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getStatefulSets(kubeClient))
-	}
-	if len(ko.PersistentVolumes) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getPersistentVolumes(kubeClient))
-	}
-	if len(ko.PersistentVolumeClaims) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getPersistentVolumeClaims(kubeClient))
-	}
-	if len(ko.Jobs) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getJobs(kubeClient))
-	}
-	if len(ko.Daemons) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getDaemons(kubeClient))
-	}
-	if len(ko.Deployments) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getDeployments(kubeClient))
-	}
-	if len(ko.ReplicaSets) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getReplicaSets(kubeClient))
-	}
-	if len(ko.StorageClasses) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getStorageClasses(kubeClient))
-	}
-	if len(ko.HorizontalPodAutoscalers) != 0 {
-		yamlFiles = appendSlice(yamlFiles, ko.getHorizontalPodAutoscalers(kubeClient))
-	}
-	return yamlFiles
+	readers := []struct {
+		names []string
+		get   func(clientset.Interface) ([]string, error)
+	}{
+		{ko.Pods, ko.getPods},
+		{ko.Services, ko.getServices},
+		{ko.ReplicationControllers, ko.getReplicationControllers},
+		{ko.Secrets, ko.getSecrets},
+		{ko.ConfigMaps, ko.getConfigMaps},
+		{ko.StatefulSets, ko.getStatefulSets},
+		{ko.PersistentVolumes, ko.getPersistentVolumes},
+		{ko.PersistentVolumeClaims, ko.getPersistentVolumeClaims},
+		{ko.Jobs, ko.getJobs},
+		{ko.CronJobs, ko.getCronJobs},
+		{ko.Daemons, ko.getDaemons},
+		{ko.Deployments, ko.getDeployments},
+		{ko.ReplicaSets, ko.getReplicaSets},
+		{ko.StorageClasses, ko.getStorageClasses},
+		{ko.HorizontalPodAutoscalers, ko.getHorizontalPodAutoscalers},
+	}
+	for _, r := range readers {
+		if len(r.names) == 0 {
+			continue
+		}
+		files, err := r.get(kubeClient)
+		if err != nil {
+			return nil, err
+		}
+		yamlFiles = appendSlice(yamlFiles, files)
+	}
+	return yamlFiles, nil
 }
 
-func (ko KubeObjects) getPods(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getPods(kubeClient clientset.Interface) ([]string, error) {
 	var yamlFiles []string
 	for _, v := range ko.Pods {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		pod, err := kubeClient.CoreV1().Pods(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(scheme.Scheme, pod)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if pod.Kind == "" {
 			pod.Kind = ref.Kind
@@ -131,24 +121,27 @@ func (ko KubeObjects) getPods(kubeClient clientset.Interface) []string {
 		pod.Status = corev1.PodStatus{}
 		dataByte, err := yaml.Marshal(pod)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		yamlFiles = append(yamlFiles, string(dataByte))
 	}
-	return yamlFiles
+	return yamlFiles, nil
 }
 
-func (ko KubeObjects) getReplicationControllers(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getReplicationControllers(kubeClient clientset.Interface) ([]string, error) {
 	var yamlFiles []string
 	for _, v := range ko.ReplicationControllers {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		rc, err := kubeClient.CoreV1().ReplicationControllers(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, rc)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if rc.Kind == "" {
 			rc.Kind = ref.Kind
@@ -159,25 +152,28 @@ func (ko KubeObjects) getReplicationControllers(kubeClient clientset.Interface)
 		rc.Status = corev1.ReplicationControllerStatus{}
 		dataByte, err := yaml.Marshal(rc)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		yamlFiles = append(yamlFiles, string(dataByte))
 	}
-	return yamlFiles
+	return yamlFiles, nil
 }
 
-func (ko KubeObjects) getServices(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getServices(kubeClient clientset.Interface) ([]string, error) {
 	var yamlFiles []string
 
 	for _, v := range ko.Services {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		service, err := kubeClient.CoreV1().Services(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, service)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if service.Kind == "" {
 			service.Kind = ref.Kind
@@ -188,24 +184,27 @@ func (ko KubeObjects) getServices(kubeClient clientset.Interface) []string {
 		service.Status = corev1.ServiceStatus{}
 		dataByte, err := yaml.Marshal(service)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		yamlFiles = append(yamlFiles, string(dataByte))
 	}
-	return yamlFiles
+	return yamlFiles, nil
 }
 
-func (ko KubeObjects) getSecrets(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getSecrets(kubeClient clientset.Interface) ([]string, error) {
 	var yamlFiles []string
 	for _, v := range ko.Secrets {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		secret, err := kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, secret)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if secret.Kind == "" {
 			secret.Kind = ref.Kind
@@ -215,25 +214,28 @@ func (ko KubeObjects) getSecrets(kubeClient clientset.Interface) []string {
 		}
 		dataByte, err := yaml.Marshal(secret)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		yamlFiles = append(yamlFiles, string(dataByte))
 	}
-	return yamlFiles
+	return yamlFiles, nil
 }
 
-func (ko KubeObjects) getConfigMaps(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getConfigMaps(kubeClient clientset.Interface) ([]string, error) {
 	var yamlFiles []string
 
 	for _, v := range ko.ConfigMaps {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		configmap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, configmap)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if configmap.Kind == "" {
 			configmap.Kind = ref.Kind
@@ -243,51 +245,56 @@ func (ko KubeObjects) getConfigMaps(kubeClient clientset.Interface) []string {
 		}
 		dataByte, err := yaml.Marshal(configmap)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		yamlFiles = append(yamlFiles, string(dataByte))
 	}
-	return yamlFiles
+	return yamlFiles, nil
 }
 
-func (ko KubeObjects) getStatefulSets(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getStatefulSets(kubeClient clientset.Interface) ([]string, error) {
 	var yamlFiles []string
 	for _, v := range ko.StatefulSets {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		statefulset, err := kubeClient.AppsV1().StatefulSets(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, statefulset)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if statefulset.Kind == "" {
 			statefulset.Kind = ref.Kind
 		}
 		if len(statefulset.APIVersion) == 0 {
-			statefulset.APIVersion = makeAPIVersion(statefulset.GetSelfLink())
+			if statefulset.APIVersion, err = makeAPIVersion(statefulset.GetSelfLink()); err != nil {
+				return nil, err
+			}
 		}
 		statefulset.Status = apps.StatefulSetStatus{}
 		dataByte, err := yaml.Marshal(statefulset)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		yamlFiles = append(yamlFiles, string(dataByte))
 	}
-	return yamlFiles
+	return yamlFiles, nil
 }
 
-func (ko KubeObjects) getPersistentVolumes(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getPersistentVolumes(kubeClient clientset.Interface) ([]string, error) {
 	var yamlFiles []string
 	for _, v := range ko.PersistentVolumes {
 		pv, err := kubeClient.CoreV1().PersistentVolumes().Get(context.TODO(), v, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, pv)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if pv.Kind == "" {
 			pv.Kind = ref.Kind
@@ -297,24 +304,27 @@ func (ko KubeObjects) getPersistentVolumes(kubeClient clientset.Interface) []str
 		}
 		dataByte, err := yaml.Marshal(pv)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		yamlFiles = append(yamlFiles, string(dataByte))
 	}
-	return yamlFiles
+	return yamlFiles, nil
 }
 
-func (ko KubeObjects) getPersistentVolumeClaims(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getPersistentVolumeClaims(kubeClient clientset.Interface) ([]string, error) {
 	var yamlFiles []string
 	for _, v := range ko.PersistentVolumeClaims {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		pvc, err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, pvc)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if pvc.Kind == "" {
 			pvc.Kind = ref.Kind
@@ -324,162 +334,223 @@ func (ko KubeObjects) getPersistentVolumeClaims(kubeClient clientset.Interface)
 		}
 		dataByte, err := yaml.Marshal(pvc)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		yamlFiles = append(yamlFiles, string(dataByte))
 	}
-	return yamlFiles
+	return yamlFiles, nil
 }
 
-func (ko KubeObjects) getJobs(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getJobs(kubeClient clientset.Interface) ([]string, error) {
 	var jobFiles []string
 	for _, v := range ko.Jobs {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		job, err := kubeClient.BatchV1().Jobs(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, job)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if job.Kind == "" {
 			job.Kind = ref.Kind
 		}
 		if job.APIVersion == "" {
-			job.APIVersion = makeAPIVersion(job.GetSelfLink())
+			if job.APIVersion, err = makeAPIVersion(job.GetSelfLink()); err != nil {
+				return nil, err
+			}
 		}
 		job.Status = batch.JobStatus{}
 		dataByte, err := yaml.Marshal(job)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		jobFiles = append(jobFiles, string(dataByte))
 	}
-	return jobFiles
+	return jobFiles, nil
+}
+
+func (ko KubeObjects) getCronJobs(kubeClient clientset.Interface) ([]string, error) {
+	var cronJobFiles []string
+	for _, v := range ko.CronJobs {
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
+		cronJob, err := kubeClient.BatchV1().CronJobs(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		ref, err := reference.GetReference(api.Scheme, cronJob)
+		if err != nil {
+			return nil, err
+		}
+		if cronJob.Kind == "" {
+			cronJob.Kind = ref.Kind
+		}
+		if cronJob.APIVersion == "" {
+			if cronJob.APIVersion, err = makeAPIVersion(cronJob.GetSelfLink()); err != nil {
+				return nil, err
+			}
+		}
+		cronJob.Status = batch.CronJobStatus{}
+		dataByte, err := yaml.Marshal(cronJob)
+		if err != nil {
+			return nil, err
+		}
+		cronJobFiles = append(cronJobFiles, string(dataByte))
+	}
+	return cronJobFiles, nil
 }
 
-func (ko KubeObjects) getDaemons(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getDaemons(kubeClient clientset.Interface) ([]string, error) {
 	var daemonFiles []string
 	for _, v := range ko.Daemons {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		daemon, err := kubeClient.ExtensionsV1beta1().DaemonSets(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, daemon)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if daemon.Kind == "" {
 			daemon.Kind = ref.Kind
 		}
 		if daemon.APIVersion == "" {
-			daemon.APIVersion = makeAPIVersion(daemon.GetSelfLink())
+			if daemon.APIVersion, err = makeAPIVersion(daemon.GetSelfLink()); err != nil {
+				return nil, err
+			}
 		}
 		daemon.Status = extensions.DaemonSetStatus{}
 		dataByte, err := yaml.Marshal(daemon)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		daemonFiles = append(daemonFiles, string(dataByte))
 
 	}
-	return daemonFiles
+	return daemonFiles, nil
 }
 
-func (ko KubeObjects) getDeployments(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getDeployments(kubeClient clientset.Interface) ([]string, error) {
 	var files []string
 	for _, v := range ko.Deployments {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		deployment, err := kubeClient.ExtensionsV1beta1().Deployments(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, deployment)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if deployment.Kind == "" {
 			deployment.Kind = ref.Kind
 		}
 		if deployment.APIVersion == "" {
-			deployment.APIVersion = makeAPIVersion(deployment.GetSelfLink())
+			if deployment.APIVersion, err = makeAPIVersion(deployment.GetSelfLink()); err != nil {
+				return nil, err
+			}
 		}
 		deployment.Status = extensions.DeploymentStatus{}
 		dataByte, err := yaml.Marshal(deployment)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		files = append(files, string(dataByte))
 
 	}
-	return files
+	return files, nil
 }
 
-func (ko KubeObjects) getReplicaSets(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getReplicaSets(kubeClient clientset.Interface) ([]string, error) {
 	var yamlFiles []string
 	for _, v := range ko.ReplicaSets {
-		objectName, namespace := splitNamespace(v)
+		objectName, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		rs, err := kubeClient.ExtensionsV1beta1().ReplicaSets(namespace).Get(context.TODO(), objectName, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, rs)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if rs.Kind == "" {
 			rs.Kind = ref.Kind
 		}
 		if rs.APIVersion == "" {
-			rs.APIVersion = makeAPIVersion(rs.GetSelfLink())
+			if rs.APIVersion, err = makeAPIVersion(rs.GetSelfLink()); err != nil {
+				return nil, err
+			}
 		}
 		rs.Status = extensions.ReplicaSetStatus{}
 		dataByte, err := yaml.Marshal(rs)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		yamlFiles = append(yamlFiles, string(dataByte))
 	}
-	return yamlFiles
+	return yamlFiles, nil
 }
 
-func (ko KubeObjects) getStorageClasses(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getStorageClasses(kubeClient clientset.Interface) ([]string, error) {
 	var storageFiles []string
 	for _, v := range ko.StorageClasses {
 		// objectsName, namespace := splitnamespace(v)
 		storageClass, err := kubeClient.StorageV1().StorageClasses().Get(context.TODO(), v, metav1.GetOptions{})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		ref, err := reference.GetReference(api.Scheme, storageClass)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		if storageClass.Kind == "" {
 			storageClass.Kind = ref.Kind
 		}
 		if storageClass.APIVersion == "" {
-			storageClass.APIVersion = makeAPIVersion(storageClass.GetSelfLink())
+			if storageClass.APIVersion, err = makeAPIVersion(storageClass.GetSelfLink()); err != nil {
+				return nil, err
+			}
 		}
 		dataByte, err := yaml.Marshal(storageClass)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		storageFiles = append(storageFiles, string(dataByte))
 
 	}
 
-	return storageFiles
+	return storageFiles, nil
 }
 
-func (ko KubeObjects) getHorizontalPodAutoscalers(kubeClient clientset.Interface) []string {
+func (ko KubeObjects) getHorizontalPodAutoscalers(kubeClient clientset.Interface) ([]string, error) {
 	var horizontalPodAutoscalers []string
 	for _, v := range ko.HorizontalPodAutoscalers {
-		_, namespace := splitNamespace(v)
+		_, namespace, err := splitNamespace(v)
+		if err != nil {
+			return nil, err
+		}
 		horizontalPodAutoscaler := kubeClient.AutoscalingV1().HorizontalPodAutoscalers(namespace)
-		scaler, _ := horizontalPodAutoscaler.List(context.TODO(), metav1.ListOptions{})
+		scaler, err := horizontalPodAutoscaler.List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
 
 		for _, item := range scaler.Items {
 			if item.Kind == "" {
@@ -487,18 +558,20 @@ func (ko KubeObjects) getHorizontalPodAutoscalers(kubeClient clientset.Interface
 			}
 
 			if item.APIVersion == "" {
-				item.APIVersion = makeAPIVersion(item.GetSelfLink())
+				if item.APIVersion, err = makeAPIVersion(item.GetSelfLink()); err != nil {
+					return nil, err
+				}
 			}
 
 			dataByte, err := yaml.Marshal(item)
 			if err != nil {
-				log.Fatal(err)
+				return nil, err
 			}
 			horizontalPodAutoscalers = append(horizontalPodAutoscalers, string(dataByte))
 		}
 	}
 
-	return horizontalPodAutoscalers
+	return horizontalPodAutoscalers, nil
 }
 
 func newKubeClient() (clientset.Interface, error) {
@@ -519,22 +592,20 @@ func appendSlice(mainSlice []string, subSlice []string) []string {
 	return mainSlice
 }
 
-func splitNamespace(s string) (string, string) {
+func splitNamespace(s string) (string, string, error) {
 	str := strings.Split(s, "@")
 	if len(str) == 2 {
-		return str[0], str[1]
+		return str[0], str[1], nil
 	} else if len(str) == 1 {
-		return str[0], corev1.NamespaceDefault
+		return str[0], corev1.NamespaceDefault, nil
 	}
-	log.Fatal("ERROR : Can not detect Namespace")
-	return "", ""
+	return "", "", fmt.Errorf("can not detect namespace of %q", s)
 }
 
-func makeAPIVersion(selfLink string) string {
+func makeAPIVersion(selfLink string) (string, error) {
 	str := strings.Split(selfLink, "/")
-	if len(str) > 2 {
-		return str[2] + "/" + str[3]
+	if len(str) > 3 {
+		return str[2] + "/" + str[3], nil
 	}
-	log.Fatal("api version not found")
-	return ""
+	return "", fmt.Errorf("api version not found in selfLink %q", selfLink)
 }
@@ -0,0 +1,24 @@
+package pkg
+
+import (
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/lint"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// LintChart runs Helm's chart linter on chartDir with the default values and
+// prints its findings. Findings about templates name the template file, and
+// the line when Helm reports one. It returns a LintError if any finding is an
+// error.
+func LintChart(chartDir string) error {
+	fmt.Fprintln(LogOutput, "Linting chart...")
+	linter := lint.All(chartDir, nil, verifyRelease.Namespace, false)
+	for _, msg := range linter.Messages {
+		fmt.Fprintln(LogOutput, "  "+msg.Error())
+	}
+	if linter.HighestSeverity >= support.ErrorSev {
+		return LintError(linter.Messages)
+	}
+	return nil
+}
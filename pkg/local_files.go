@@ -0,0 +1,255 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// FileFilter narrows down the files read by ReadLocalFiles. Patterns use
+// filepath.Match syntax and are matched against both the base name and the
+// slash separated path relative to the directory being read. A file is read
+// when it matches any Include pattern (or Include is empty) and no Exclude
+// pattern. Excluding a directory skips everything below it.
+type FileFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// ReadLocalFiles walks dirName recursively and reads every .yaml, .yml and
+// .json file accepted by filter, splitting them into single Kubernetes
+// objects (see SplitDocuments). Hidden directories such as .git are skipped.
+// Along with each object it returns where it was read from, for use as
+// Generator.YamlSources.
+func ReadLocalFiles(dirName string, filter FileFilter) ([]string, []string, error) {
+	var yamlFiles, sources []string
+	err := filepath.WalkDir(dirName, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dirName {
+			return nil
+		}
+		rel, err := filepath.Rel(dirName, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") || filter.excluded(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !filter.included(rel) || filter.excluded(rel) {
+			return nil
+		}
+		if !isManifestFile(d.Name()) {
+			fmt.Fprintf(LogOutput, "skipping %s (%s)\n", rel, filepath.Ext(d.Name()))
+			return nil
+		}
+
+		dataByte, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		objects, objectSources, err := SplitDocuments(dataByte, path)
+		if err != nil {
+			return err
+		}
+		yamlFiles = append(yamlFiles, objects...)
+		sources = append(sources, objectSources...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return yamlFiles, sources, nil
+}
+
+// ReadFiles reads Kubernetes objects from every entry of names and returns
+// them as one set, in order. An entry may be a directory (read like
+// ReadLocalFiles, using filter), a single file of any extension, or "-" to
+// read a YAML/JSON stream from stdin.
+func ReadFiles(names []string, filter FileFilter, stdin io.Reader) ([]string, []string, error) {
+	var yamlFiles, sources []string
+	stdinRead := false
+	for _, name := range names {
+		if name == "-" {
+			if stdinRead {
+				return nil, nil, fmt.Errorf("stdin can only be read once")
+			}
+			stdinRead = true
+		}
+		objects, objectSources, err := readFile(name, filter, stdin)
+		if err != nil {
+			return nil, nil, err
+		}
+		yamlFiles = append(yamlFiles, objects...)
+		sources = append(sources, objectSources...)
+	}
+	return yamlFiles, sources, nil
+}
+
+func readFile(name string, filter FileFilter, stdin io.Reader) ([]string, []string, error) {
+	if name == "-" {
+		dataByte, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading stdin: %v", err)
+		}
+		return SplitDocuments(dataByte, "<stdin>")
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fi.IsDir() {
+		return ReadLocalFiles(name, filter)
+	}
+	dataByte, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return SplitDocuments(dataByte, name)
+}
+
+func isManifestFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func (f FileFilter) included(rel string) bool {
+	if len(f.Include) == 0 {
+		return true
+	}
+	return matchAny(f.Include, rel)
+}
+
+func (f FileFilter) excluded(rel string) bool {
+	return matchAny(f.Exclude, rel)
+}
+
+func matchAny(patterns []string, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitDocuments splits a YAML stream into one string per Kubernetes object.
+// Documents separated by "---" become separate objects, and the items of a
+// List (kind: List or any *List kind) are unwrapped. Empty documents and
+// documents without a kind, which are not Kubernetes objects, are dropped.
+//
+// For each object the returned sources name where it came from in source:
+// "file.yaml" for a single document, "file.yaml[2]" for the third document of
+// a stream and "file.yaml[0].items[1]" for an item of a list.
+func SplitDocuments(data []byte, source string) ([]string, []string, error) {
+	var docs [][]byte
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", source, err)
+		}
+		docs = append(docs, doc)
+	}
+
+	var objects, sources []string
+	for i, doc := range docs {
+		docSource := source
+		if len(docs) > 1 {
+			docSource = fmt.Sprintf("%s[%d]", source, i)
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal(doc, &obj); err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", docSource, err)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		if _, ok := obj["kind"]; !ok {
+			fmt.Fprintf(LogOutput, "skipping %s (not a Kubernetes object)\n", docSource)
+			continue
+		}
+		if !isList(obj) {
+			objects = append(objects, string(doc))
+			sources = append(sources, docSource)
+			continue
+		}
+		items, err := listItems(obj)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", docSource, err)
+		}
+		for j, item := range items {
+			objects = append(objects, item)
+			sources = append(sources, fmt.Sprintf("%s.items[%d]", docSource, j))
+		}
+	}
+	return objects, sources, nil
+}
+
+func isList(obj map[string]interface{}) bool {
+	kind, _ := obj["kind"].(string)
+	if !strings.HasSuffix(kind, "List") {
+		return false
+	}
+	_, ok := obj["items"]
+	return ok
+}
+
+// listItems returns the items of a List as yaml. Items of typed lists (e.g.
+// DeploymentList) usually omit apiVersion and kind, so they are filled in
+// from the list itself.
+func listItems(list map[string]interface{}) ([]string, error) {
+	items, ok := list["items"].([]interface{})
+	if !ok {
+		if list["items"] == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("items of %v is not a list", list["kind"])
+	}
+	kind := strings.TrimSuffix(list["kind"].(string), "List")
+	var result []string
+	for i, v := range items {
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("items[%d] is not an object", i)
+		}
+		if _, found := item["kind"]; !found && kind != "" {
+			item["kind"] = kind
+		}
+		if _, found := item["apiVersion"]; !found {
+			if apiVersion, ok := list["apiVersion"]; ok {
+				item["apiVersion"] = apiVersion
+			}
+		}
+		data, err := yaml.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("items[%d]: %v", i, err)
+		}
+		result = append(result, string(data))
+	}
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/appscode/go/encoding/yaml"
+	apiv1 "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const notesHeader = `Thank you for installing {{ .Chart.Name }}. Your release is named {{ .Release.Name }}.
+`
+
+// notesTemplate returns the NOTES.txt of a chart made of objects, telling how
+// to reach its Services and Ingresses, or "" if it has none.
+func notesTemplate(objects []string) (string, error) {
+	var notes []string
+	for _, obj := range objects {
+		kubeJson, err := yaml.ToJSON([]byte(obj))
+		if err != nil {
+			return "", err
+		}
+		var typeMeta metav1.TypeMeta
+		if err := json.Unmarshal(kubeJson, &typeMeta); err != nil {
+			return "", err
+		}
+		switch typeMeta.GroupVersionKind() {
+		case apiv1.SchemeGroupVersion.WithKind("Service"):
+			svc := apiv1.Service{}
+			if err := json.Unmarshal(kubeJson, &svc); err != nil {
+				return "", err
+			}
+			if note := serviceNotes(svc); len(note) != 0 {
+				notes = append(notes, note)
+			}
+		case networking.SchemeGroupVersion.WithKind("Ingress"):
+			ingress := networking.Ingress{}
+			if err := json.Unmarshal(kubeJson, &ingress); err != nil {
+				return "", err
+			}
+			if note := ingressNotes(ingress); len(note) != 0 {
+				notes = append(notes, note)
+			}
+		}
+	}
+	if len(notes) == 0 {
+		return "", nil
+	}
+	return notesHeader + strings.Join(notes, ""), nil
+}
+
+// notesObjectName returns the templated name and namespace of an object, as
+// generateObjectMetaTemplate sets them.
+func notesObjectName(objectMeta metav1.ObjectMeta, key string) (string, string) {
+	name := objectMeta.Name
+	if !PreserveName {
+		name = fmt.Sprintf("%s-%s", includeHelper("fullname"), objectMeta.Name)
+	}
+	namespace := "{{ .Release.Namespace }}"
+	if len(objectMeta.Namespace) != 0 {
+		namespace = fmt.Sprintf("{{ .Values.%s.%s }}", key, Namespace)
+	}
+	return name, namespace
+}
+
+// serviceNotes tells how to reach the first port of svc for each of the
+// service types its serviceType value can be set to.
+func serviceNotes(svc apiv1.Service) string {
+	if len(svc.Spec.Ports) == 0 {
+		return ""
+	}
+	key := valueKey(svc.Name, "svc")
+	name, namespace := notesObjectName(svc.ObjectMeta, key)
+	port := svc.Spec.Ports[0].Port
+	localPort := port
+	if localPort < 1024 {
+		localPort = 8080
+	}
+	clusterIP := fmt.Sprintf(`  kubectl --namespace %[1]s port-forward svc/%[2]s %[3]d:%[4]d
+  echo "Visit http://127.0.0.1:%[3]d"
+`, namespace, name, localPort, port)
+	if len(svc.Spec.Type) == 0 {
+		return fmt.Sprintf("\nService %s:\n%s", name, clusterIP)
+	}
+	return fmt.Sprintf(`
+Service %[1]s:
+{{- if eq .Values.%[3]s.%[4]s "NodePort" }}
+  export NODE_PORT=$(kubectl get --namespace %[2]s -o jsonpath="{.spec.ports[0].nodePort}" services %[1]s)
+  export NODE_IP=$(kubectl get nodes -o jsonpath="{.items[0].status.addresses[0].address}")
+  echo http://$NODE_IP:$NODE_PORT
+{{- else if eq .Values.%[3]s.%[4]s "LoadBalancer" }}
+  NOTE: It may take a few minutes for the LoadBalancer IP to be available.
+        You can watch its status by running 'kubectl get --namespace %[2]s svc -w %[1]s'
+  export SERVICE_IP=$(kubectl get svc --namespace %[2]s %[1]s --template "{{"{{ range (index .status.loadBalancer.ingress 0) }}{{ . }}{{ end }}"}}")
+  echo http://$SERVICE_IP:%[5]d
+{{- else if eq .Values.%[3]s.%[4]s "ClusterIP" }}
+%[6]s
+{{- end }}
+`, name, namespace, key, ServiceType, port, strings.TrimSuffix(clusterIP, "\n"))
+}
+
+// ingressNotes lists the URLs of the hosts of ingress, when it is enabled.
+func ingressNotes(ingress networking.Ingress) string {
+	if len(ingress.Spec.Rules) == 0 {
+		return ""
+	}
+	key := valueKey(ingress.Name, "ingress")
+	name, _ := notesObjectName(ingress.ObjectMeta, key)
+	return fmt.Sprintf(`{{- if .Values.%[2]s.%[3]s }}
+
+Ingress %[1]s:
+{{- range $host := .Values.%[2]s.%[4]s }}
+{{- if $host.host }}
+{{- range $host.%[5]s }}
+  http{{ if $.Values.%[2]s.%[6]s }}s{{ end }}://{{ $host.host }}{{ .path }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+`, name, key, Enabled, Hosts, Paths, TLS)
+}
@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// LogOutput is where the progress of commands is printed.
+var LogOutput io.Writer = os.Stdout
+
+// ChartWriter receives the files of a chart made by Generator.Create. name is
+// the slash separated path of a file in the chart, e.g. templates/web.svc.yaml.
+type ChartWriter interface {
+	WriteFile(name string, data []byte) error
+}
+
+// dirWriter writes the files of a chart into the chart directory.
+type dirWriter string
+
+func (d dirWriter) WriteFile(name string, data []byte) error {
+	filename := filepath.Join(string(d), filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0o644)
+}
+
+// StreamWriter prints the files of a chart to W as one YAML stream, each file
+// preceded by a "# Source:" comment with its path, like helm template does.
+type StreamWriter struct {
+	W io.Writer
+}
+
+func (s StreamWriter) WriteFile(name string, data []byte) error {
+	if len(data) != 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	_, err := fmt.Fprintf(s.W, "---\n# Source: %s\n%s", name, data)
+	return err
+}
+
+// MemoryWriter keeps the files of a chart in memory, for library callers to
+// inspect the chart without writing it to disk.
+type MemoryWriter struct {
+	Files []*loader.BufferedFile
+}
+
+func (m *MemoryWriter) WriteFile(name string, data []byte) error {
+	m.Files = append(m.Files, &loader.BufferedFile{Name: name, Data: data})
+	return nil
+}
+
+// Chart loads the files written so far as a chart.
+func (m *MemoryWriter) Chart() (*chart.Chart, error) {
+	return loader.LoadFiles(m.Files)
+}
@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// IndexName is the name of the index file of a chart repository.
+const IndexName = "index.yaml"
+
+// PackageChart archives the chart in chartDir as <name>-<version>.tgz in
+// destDir, creating destDir if needed. It returns the path of the archive.
+func PackageChart(chartDir, destDir string) (string, error) {
+	c, err := loader.LoadDir(chartDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return "", err
+	}
+	archive, err := chartutil.Save(c, destDir)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintln(LogOutput, "Packaged chart to", archive)
+	return archive, nil
+}
+
+// IndexRepository writes the index.yaml of the chart repository made of the
+// chart archives in dir. The charts are listed under url, or relative to the
+// index when url is empty.
+func IndexRepository(dir, url string) error {
+	index, err := repo.IndexDirectory(dir, url)
+	if err != nil {
+		return err
+	}
+	index.SortEntries()
+	return index.WriteFile(filepath.Join(dir, IndexName), 0o644)
+}
@@ -5,28 +5,55 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	appsv1 "k8s.io/api/apps/v1"
-	"log"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
 
 	"github.com/ghodss/yaml"
 	"helm.sh/helm/v3/pkg/chart"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/autoscaling/v1"
+	batch "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	networking "k8s.io/api/networking/v1"
+	rbac "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var PreserveName bool
 
+// chartNamePlaceholder stands in for the name of the chart in generated
+// templates, until they are written to the chart by chartTemplate. Helper
+// templates are prefixed with the chart name, so they don't collide when the
+// chart is a subchart.
+const chartNamePlaceholder = "<CHARTNAME>"
+
+// includeHelper returns the action that includes the named helper template.
+func includeHelper(name string) string {
+	return fmt.Sprintf(`{{ include %q . }}`, helperName(name))
+}
+
+// chartTemplate returns a generated template as it is written to the chart
+// named chartName.
+func chartTemplate(template string, chartName string) string {
+	return strings.ReplaceAll(template, chartNamePlaceholder, chartName)
+}
+
+// recommendedLabels are set by the labels helper template.
+var recommendedLabels = []string{
+	"helm.sh/chart",
+	"app.kubernetes.io/name",
+	"app.kubernetes.io/instance",
+	"app.kubernetes.io/version",
+	"app.kubernetes.io/managed-by",
+}
+
 func generateObjectMetaTemplate(objectMeta metav1.ObjectMeta, key string, value map[string]interface{}, extraTagForName string) metav1.ObjectMeta {
 	if !PreserveName {
 		if len(objectMeta.Name) != 0 {
-			objectMeta.Name = fmt.Sprintf(`{{ template "fullname" . }}`)
+			objectMeta.Name = includeHelper("fullname")
 		}
 		if len(extraTagForName) != 0 {
 			objectMeta.Name = fmt.Sprintf("%s-%s", objectMeta.Name, extraTagForName)
@@ -45,79 +72,194 @@ func generateObjectMetaTemplate(objectMeta metav1.ObjectMeta, key string, value
 		objectMeta.Namespace = fmt.Sprintf("{{.Values.%s.%s}}", key, Namespace)
 	}
 	objectMeta.Labels = generateTemplateForLables(objectMeta.Labels)
+	value[Annotations] = stringMapValue(objectMeta.Annotations)
+	objectMeta.Annotations = nil
+	return objectMeta
+}
+
+// generatePodTemplateMetaTemplate moves the annotations of a pod template to
+// values, next to the labels to add to it.
+func generatePodTemplateMetaTemplate(objectMeta metav1.ObjectMeta, value map[string]interface{}) metav1.ObjectMeta {
+	value[PodAnnotations] = stringMapValue(objectMeta.Annotations)
+	value[PodLabels] = map[string]interface{}{}
+	objectMeta.Annotations = nil
 	return objectMeta
 }
 
-func generateTemplateReplicationCtrSpec(rcSpec apiv1.ReplicationControllerSpec, rcSpecStr string, key string, value map[string]interface{}) (string, map[string]interface{}) {
-	templateDeployment := rcSpecStr
+func stringMapValue(m map[string]string) map[string]interface{} {
+	value := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		value[k] = v
+	}
+	return value
+}
+
+// setObjectMetaInTemplate merges the labels of the chart, and the common
+// labels and annotations and the annotations of the object from values, into
+// the metadata at path.
+func setObjectMetaInTemplate(tw *templateWriter, key string, path ...string) error {
+	if err := tw.MergeInclude(helperName("labels"), subPath(path, "metadata", "labels")...); err != nil {
+		return err
+	}
+	return mergeMetaInTemplate(tw, subPath(path, "metadata"),
+		[]string{".Values." + CommonLabels},
+		[]string{".Values." + CommonAnnotations, fmt.Sprintf(".Values.%s.%s", key, Annotations)})
+}
 
-	templateDeployment = updateIntParamAsStringInTemplate(templateDeployment, key, "replicas")
+// setSelectorInTemplate merges the selector labels of the chart into the
+// label selector at path, which the pod template labels have too.
+func setSelectorInTemplate(tw *templateWriter, path ...string) error {
+	return tw.MergeInclude(helperName("selectorLabels"), path...)
+}
+
+func helperName(name string) string {
+	return chartNamePlaceholder + "." + name
+}
+
+func mergeMetaInTemplate(tw *templateWriter, path []string, labels []string, annotations []string) error {
+	for _, pipeline := range labels {
+		if err := tw.Merge(pipeline, subPath(path, "labels")...); err != nil {
+			return err
+		}
+	}
+	for _, pipeline := range annotations {
+		if err := tw.Merge(pipeline, subPath(path, Annotations)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func generateTemplateReplicationCtrSpec(rcSpec apiv1.ReplicationControllerSpec, tw *templateWriter, key string, value map[string]interface{}) error {
 	value["replicas"] = rcSpec.Replicas
+	if rcSpec.Replicas != nil {
+		if err := setIntParamInTemplate(tw, key, "replicas", "spec"); err != nil {
+			return err
+		}
+	}
 
 	if rcSpec.MinReadySeconds != 0 {
-		templateDeployment = updateIntParamAsStringInTemplate(templateDeployment, key, "minReadySeconds")
 		value["minReadySeconds"] = rcSpec.MinReadySeconds
+		if err := setIntParamInTemplate(tw, key, "minReadySeconds", "spec"); err != nil {
+			return err
+		}
 	}
 
-	return templateDeployment, value
+	return nil
 }
 
-func generateTemplateReplicaSetSpec(rsSpec extensions.ReplicaSetSpec, rsSpecStr string, key string, value map[string]interface{}) (string, map[string]interface{}) {
-	templateDeployment := rsSpecStr
-
-	templateDeployment = updateIntParamAsStringInTemplate(templateDeployment, key, "replicas")
+func generateTemplateReplicaSetSpec(rsSpec extensions.ReplicaSetSpec, tw *templateWriter, key string, value map[string]interface{}) error {
 	value["replicas"] = rsSpec.Replicas
+	if rsSpec.Replicas != nil {
+		if err := setIntParamInTemplate(tw, key, "replicas", "spec"); err != nil {
+			return err
+		}
+	}
 
 	if rsSpec.MinReadySeconds != 0 {
-		templateDeployment = updateIntParamAsStringInTemplate(templateDeployment, key, "minReadySeconds")
 		value["minReadySeconds"] = rsSpec.MinReadySeconds
+		if err := setIntParamInTemplate(tw, key, "minReadySeconds", "spec"); err != nil {
+			return err
+		}
 	}
 
-	return templateDeployment, value
+	return nil
 }
 
-func generateTemplateDeplymentSpec(dcSpec appsv1.DeploymentSpec, dcSpecStr string, key string, value map[string]interface{}) (string, map[string]interface{}) {
-	templateDeployment := dcSpecStr
-	templateDeployment = updateIntParamAsStringInTemplate(templateDeployment, key, "replicas")
+func generateTemplateDeplymentSpec(dcSpec appsv1.DeploymentSpec, tw *templateWriter, key string, value map[string]interface{}) error {
 	value["replicas"] = dcSpec.Replicas
+	if dcSpec.Replicas != nil {
+		if err := setIntParamInTemplate(tw, key, "replicas", "spec"); err != nil {
+			return err
+		}
+	}
 
 	if dcSpec.MinReadySeconds != 0 {
-		templateDeployment = updateIntParamAsStringInTemplate(templateDeployment, key, "minReadySeconds")
 		value["minReadySeconds"] = dcSpec.MinReadySeconds
+		if err := setIntParamInTemplate(tw, key, "minReadySeconds", "spec"); err != nil {
+			return err
+		}
 	}
 
 	if dcSpec.RevisionHistoryLimit != nil {
-		templateDeployment = updateIntParamAsStringInTemplate(templateDeployment, key, "revisionHistoryLimit")
 		value["revisionHistoryLimit"] = dcSpec.RevisionHistoryLimit
+		if err := setIntParamInTemplate(tw, key, "revisionHistoryLimit", "spec"); err != nil {
+			return err
+		}
 	}
 
-	return templateDeployment, value
+	return nil
 }
 
-func updateIntParamAsStringInTemplate(spec string, key string, replace string) string {
-	str := strings.Split(spec, "\n")
-	var tpl bytes.Buffer
-	for _, l := range str {
-		if len(l) == 0 {
-			continue
+func generateTemplateStatefulSetSpec(ssSpec appsv1.StatefulSetSpec, tw *templateWriter, key string, value map[string]interface{}) error {
+	value[Replicas] = ssSpec.Replicas
+	if ssSpec.Replicas != nil {
+		if err := setIntParamInTemplate(tw, key, Replicas, "spec"); err != nil {
+			return err
 		}
-		if strings.Contains(l, replace+": ") {
-			str1 := fmt.Sprintf("{{.Values.%s.%s}}", key, replace)
-			regex := regexp.MustCompile(".*" + replace + ":")
-			extrStr := regex.FindString(l)
-			tpl.WriteString(extrStr)
-			tpl.WriteString(" ")
-			tpl.WriteString(str1)
-		} else {
-			tpl.WriteString(l)
+	}
+
+	if ssSpec.MinReadySeconds != 0 {
+		value["minReadySeconds"] = ssSpec.MinReadySeconds
+		if err := setIntParamInTemplate(tw, key, "minReadySeconds", "spec"); err != nil {
+			return err
+		}
+	}
+
+	if ssSpec.RevisionHistoryLimit != nil {
+		value["revisionHistoryLimit"] = ssSpec.RevisionHistoryLimit
+		if err := setIntParamInTemplate(tw, key, "revisionHistoryLimit", "spec"); err != nil {
+			return err
 		}
-		tpl.WriteRune('\n')
 	}
-	return tpl.String()
+
+	return nil
 }
 
-func generateTemplateForPodSpec(podSpec apiv1.PodSpec, key string, value map[string]interface{}) apiv1.PodSpec {
-	podSpec.Containers = generateTemplateForContainer(podSpec.Containers, key, value)
+func generateTemplateForCronJobSpec(cronJobSpec batch.CronJobSpec, tw *templateWriter, key string, value map[string]interface{}) error {
+	// suspend is always templated, also when it is false and was dropped
+	// by removeEmptyFields, to keep the cron job suspendable from values.
+	value[Suspend] = cronJobSpec.Suspend != nil && *cronJobSpec.Suspend
+	if err := setIntParamInTemplate(tw, key, Suspend, "spec"); err != nil {
+		return err
+	}
+
+	if cronJobSpec.StartingDeadlineSeconds != nil {
+		value[StartingDeadlineSeconds] = cronJobSpec.StartingDeadlineSeconds
+		if err := setIntParamInTemplate(tw, key, StartingDeadlineSeconds, "spec"); err != nil {
+			return err
+		}
+	}
+
+	if cronJobSpec.SuccessfulJobsHistoryLimit != nil {
+		value[SuccessfulJobsHistoryLimit] = cronJobSpec.SuccessfulJobsHistoryLimit
+		if err := setIntParamInTemplate(tw, key, SuccessfulJobsHistoryLimit, "spec"); err != nil {
+			return err
+		}
+	}
+
+	if cronJobSpec.FailedJobsHistoryLimit != nil {
+		value[FailedJobsHistoryLimit] = cronJobSpec.FailedJobsHistoryLimit
+		if err := setIntParamInTemplate(tw, key, FailedJobsHistoryLimit, "spec"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setIntParamInTemplate sets path.param to the unquoted value of param, so
+// numbers and booleans keep their type.
+func setIntParamInTemplate(tw *templateWriter, key string, param string, path ...string) error {
+	return tw.SetRaw(fmt.Sprintf("{{.Values.%s.%s}}", key, param), append(path, param)...)
+}
+
+func generateTemplateForPodSpec(podSpec apiv1.PodSpec, key string, value map[string]interface{}) (apiv1.PodSpec, error) {
+	containers, err := generateTemplateForContainer(podSpec.Containers, key, value)
+	if err != nil {
+		return podSpec, err
+	}
+	podSpec.Containers = containers
 	if len(podSpec.Hostname) != 0 {
 		value[HostName] = podSpec.Hostname
 		podSpec.Hostname = fmt.Sprintf("{{.Values.%s.%s}}", key, HostName)
@@ -132,7 +274,12 @@ func generateTemplateForPodSpec(podSpec apiv1.PodSpec, key string, value map[str
 	}
 	if len(podSpec.ServiceAccountName) != 0 {
 		value[ServiceAccountName] = podSpec.ServiceAccountName
-		podSpec.ServiceAccountName = fmt.Sprintf("{{.Values.%s.%s}}", key, ServiceAccountName)
+		if !PreserveName && checkIfNameExist(podSpec.ServiceAccountName, "ServiceAccount") {
+			podSpec.ServiceAccountName = fmt.Sprintf(`{{ if .Values.%s.%s }}%s-%s{{ else }}{{.Values.%s.%s}}{{ end }}`,
+				ServiceAccount, Create, includeHelper("fullname"), podSpec.ServiceAccountName, key, ServiceAccountName)
+		} else {
+			podSpec.ServiceAccountName = fmt.Sprintf("{{.Values.%s.%s}}", key, ServiceAccountName)
+		}
 	}
 	if len(string(podSpec.RestartPolicy)) != 0 {
 		value[RestartPolicy] = string(podSpec.RestartPolicy)
@@ -152,51 +299,176 @@ func generateTemplateForPodSpec(podSpec apiv1.PodSpec, key string, value map[str
 
 	}
 
-	return podSpec
+	if err := generateTemplateForScheduling(podSpec, value); err != nil {
+		return podSpec, err
+	}
+
+	podSecurityContext, err := toTemplateNode(podSpec.SecurityContext)
+	if err != nil {
+		return podSpec, err
+	}
+	if podSecurityContext == nil {
+		podSecurityContext = map[string]interface{}{}
+	}
+	value[PodSecurityContext] = podSecurityContext
+
+	return podSpec, nil
 }
 
-func generateTemplateForHorizontalPodAutoscaler(hpaSpec v1.HorizontalPodAutoscalerSpec, hpaSpecStr, key string, value map[string]interface{}) (string, map[string]interface{}) {
-	templateHpa := hpaSpecStr
+// generateTemplateForScheduling adds the scheduling fields of the pod spec
+// to values. They are added also when the pod spec has none, so they can be
+// set per install.
+func generateTemplateForScheduling(podSpec apiv1.PodSpec, value map[string]interface{}) error {
+	fields := []struct {
+		name  string
+		obj   interface{}
+		empty interface{}
+	}{
+		{NodeSelector, podSpec.NodeSelector, map[string]interface{}{}},
+		{Tolerations, podSpec.Tolerations, []interface{}{}},
+		{Affinity, podSpec.Affinity, map[string]interface{}{}},
+		{TopologySpreadConstraints, podSpec.TopologySpreadConstraints, []interface{}{}},
+	}
+	for _, field := range fields {
+		node, err := toTemplateNode(field.obj)
+		if err != nil {
+			return err
+		}
+		if node == nil {
+			node = field.empty
+		}
+		value[field.name] = node
+	}
+	value[PriorityClassName] = podSpec.PriorityClassName
+	return nil
+}
 
+func generateTemplateForHorizontalPodAutoscaler(hpaSpec v1.HorizontalPodAutoscalerSpec, tw *templateWriter, key string, value map[string]interface{}) error {
 	if hpaSpec.MinReplicas != nil {
-		templateHpa = updateIntParamAsStringInTemplate(templateHpa, key, "minReplicas")
 		value[MinReplicas] = hpaSpec.MinReplicas
+		if err := setIntParamInTemplate(tw, key, MinReplicas, "spec"); err != nil {
+			return err
+		}
 	}
 
-	templateHpa = updateIntParamAsStringInTemplate(templateHpa, key, "maxReplicas")
 	value[MaxReplicas] = hpaSpec.MaxReplicas
+	if err := setIntParamInTemplate(tw, key, MaxReplicas, "spec"); err != nil {
+		return err
+	}
 
 	if hpaSpec.TargetCPUUtilizationPercentage != nil {
-		templateHpa = updateIntParamAsStringInTemplate(templateHpa, key, "targetCPUUtilizationPercentage")
 		value[TargetCPUUtilizationPercentage] = hpaSpec.TargetCPUUtilizationPercentage
+		if err := setIntParamInTemplate(tw, key, TargetCPUUtilizationPercentage, "spec"); err != nil {
+			return err
+		}
 	}
 
-	return templateHpa, value
+	return nil
 }
 
-func generateTemplateForVolume(volumes []apiv1.Volume, key string, value map[string]interface{}) (string, map[string]interface{}) {
-	volumeTemplate := ""
+// Paths of the pod spec in the objects that have one.
+var (
+	podSpecPath      = []string{"spec"}
+	templateSpecPath = []string{"spec", "template", "spec"}
+	cronJobSpecPath  = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+)
+
+// setPodSpecInTemplate sets the parts of the pod spec at path that are
+// rendered from template nodes: the volumes, the scheduling fields, the
+// security context, and the resources, security context and probes of the
+// containers. The labels and annotations of a pod template, the metadata
+// next to the spec, are merged from values too.
+func setPodSpecInTemplate(tw *templateWriter, podSpec apiv1.PodSpec, volumes []interface{}, key string, path ...string) error {
+	if len(path) > 1 {
+		if err := setSelectorInTemplate(tw, subPath(path[:len(path)-1], "metadata", "labels")...); err != nil {
+			return err
+		}
+		err := mergeMetaInTemplate(tw, subPath(path[:len(path)-1], "metadata"),
+			[]string{".Values." + CommonLabels, fmt.Sprintf(".Values.%s.%s", key, PodLabels)},
+			[]string{".Values." + CommonAnnotations, fmt.Sprintf(".Values.%s.%s", key, PodAnnotations)})
+		if err != nil {
+			return err
+		}
+	}
+	if len(volumes) != 0 {
+		if err := tw.Set(volumes, subPath(path, "volumes")...); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{NodeSelector, Tolerations, Affinity, TopologySpreadConstraints} {
+		block := withBlock{Value: fmt.Sprintf(".Values.%s.%s", key, name), Then: yamlExpr(".")}
+		if err := tw.Set(block, subPath(path, name)...); err != nil {
+			return err
+		}
+	}
+	block := withBlock{Value: fmt.Sprintf(".Values.%s.%s", key, PriorityClassName), Then: rawExpr("{{ . | quote }}")}
+	if err := tw.Set(block, subPath(path, PriorityClassName)...); err != nil {
+		return err
+	}
+	if err := tw.SetYaml(fmt.Sprintf(".Values.%s.%s", key, PodSecurityContext), subPath(path, SecurityContext)...); err != nil {
+		return err
+	}
+	for i, container := range podSpec.Containers {
+		containerPath := subPath(path, "containers", strconv.Itoa(i))
+		containerValue := fmt.Sprintf(".Values.%s.%s", key, generateSafeKey(container.Name))
+		if err := tw.SetYaml(fmt.Sprintf("%s.%s", containerValue, Resources), subPath(containerPath, Resources)...); err != nil {
+			return err
+		}
+		if err := tw.SetYaml(fmt.Sprintf("%s.%s", containerValue, SecurityContext), subPath(containerPath, SecurityContext)...); err != nil {
+			return err
+		}
+		for name, probe := range containerProbes(container) {
+			if probe == nil {
+				continue
+			}
+			probeValue := fmt.Sprintf("%s.%s", containerValue, name)
+			block := ifBlock{
+				Cond: fmt.Sprintf("%s.%s", probeValue, Enabled),
+				Then: yamlExpr(fmt.Sprintf("omit %s %q", probeValue, Enabled)),
+			}
+			if err := tw.Set(block, subPath(containerPath, name)...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func containerProbes(container apiv1.Container) map[string]*apiv1.Probe {
+	return map[string]*apiv1.Probe{
+		LivenessProbe:  container.LivenessProbe,
+		ReadinessProbe: container.ReadinessProbe,
+		StartupProbe:   container.StartupProbe,
+	}
+}
+
+func subPath(path []string, elems ...string) []string {
+	return append(append(make([]string, 0, len(path)+len(elems)), path...), elems...)
+}
+
+// generateTemplateForVolume returns the volumes as template nodes. Volumes
+// backed by persistent storage are rendered as an emptyDir unless enabled
+// under persistence in values.
+func generateTemplateForVolume(volumes []apiv1.Volume, key string, value map[string]interface{}) ([]interface{}, map[string]interface{}, error) {
+	var volumeTemplate []interface{}
 	ifCondition := ""
-	partialvolumeTemplate := ""
 	persistence := make(map[string]interface{}, 0)
 	for _, volume := range volumes {
 		ifCondition = ""
 		volumeMap := make(map[string]interface{}, 0)
 		volumeMap[Enabled] = true
-		var vol []apiv1.Volume
-		vol = append(vol, volume)
 		if volume.PersistentVolumeClaim != nil {
 			ifCondition = buildIfConditionForVolume(volume.PersistentVolumeClaim.ClaimName)
 			if checkIfNameExist(volume.PersistentVolumeClaim.ClaimName, "PersistentVolumeClaim") {
-				volume.PersistentVolumeClaim.ClaimName = fmt.Sprintf(`{{template "fullname"}}-%s`, volume.PersistentVolumeClaim.ClaimName)
+				volume.PersistentVolumeClaim.ClaimName = fmt.Sprintf("%s-%s", includeHelper("fullname"), volume.PersistentVolumeClaim.ClaimName)
 			}
 		} else if volume.ConfigMap != nil {
 			if checkIfNameExist(volume.ConfigMap.Name, "Configmap") {
-				volume.ConfigMap.Name = fmt.Sprintf(`{{ template "fullname" . }}-%s`, volume.ConfigMap.Name)
+				volume.ConfigMap.Name = fmt.Sprintf("%s-%s", includeHelper("fullname"), volume.ConfigMap.Name)
 			}
 		} else if volume.Secret != nil {
 			if checkIfNameExist(volume.Secret.SecretName, "Secret") {
-				volume.Secret.SecretName = fmt.Sprintf(`{{ template "fullname" . }}-%s`, volume.Secret.SecretName)
+				volume.Secret.SecretName = fmt.Sprintf("%s-%s", includeHelper("fullname"), volume.Secret.SecretName)
 			} // TODO add items
 		} else if volume.Glusterfs != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
@@ -204,32 +476,32 @@ func generateTemplateForVolume(volumes []apiv1.Volume, key string, value map[str
 			volumeMap[EndpointsName] = volume.Glusterfs.EndpointsName
 			volume.Glusterfs.EndpointsName = VolumeTemplateForElement(volume.Name, EndpointsName)
 			volume.Glusterfs.Path = VolumeTemplateForElement(volume.Name, Path)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.HostPath != nil {
 			volumeMap[Path] = volume.HostPath.Path
 			volume.HostPath.Path = VolumeTemplateForElement(volume.Name, Path)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.GCEPersistentDisk != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[PDName] = volume.GCEPersistentDisk.PDName
 			volumeMap[FSType] = volume.GCEPersistentDisk.FSType
 			volume.GCEPersistentDisk.PDName = VolumeTemplateForElement(volume.Name, PDName)
 			volume.GCEPersistentDisk.FSType = VolumeTemplateForElement(volume.Name, FSType)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.AWSElasticBlockStore != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
-			volumeMap[FSType] = volume.GCEPersistentDisk.FSType
+			volumeMap[FSType] = volume.AWSElasticBlockStore.FSType
 			volumeMap[VolumeID] = volume.AWSElasticBlockStore.VolumeID
 			volume.AWSElasticBlockStore.VolumeID = VolumeTemplateForElement(volume.Name, VolumeID)
 			volume.AWSElasticBlockStore.FSType = VolumeTemplateForElement(volume.Name, FSType)
-
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.NFS != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[Server] = volume.NFS.Server
 			volumeMap[Path] = volume.NFS.Path
-			volume.NFS.Path = fmt.Sprintf(`{{.Values.%s.%s}}`, volume.Name, Path)
-			volume.NFS.Server = fmt.Sprintf(`{{.Values.%s.%s}}`, volume.Name, Server)
-			persistence[volume.Name] = volumeMap
+			volume.NFS.Path = VolumeTemplateForElement(volume.Name, Path)
+			volume.NFS.Server = VolumeTemplateForElement(volume.Name, Server)
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.ISCSI != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[TargetPortal] = volume.ISCSI.TargetPortal
@@ -238,9 +510,9 @@ func generateTemplateForVolume(volumes []apiv1.Volume, key string, value map[str
 			volumeMap[FSType] = volume.ISCSI.FSType
 			volume.ISCSI.TargetPortal = VolumeTemplateForElement(volume.Name, TargetPortal)
 			volume.ISCSI.IQN = VolumeTemplateForElement(volume.Name, IQN)
-			volume.ISCSI.FSType = fmt.Sprintf(`{{.Values.%s.%s}}`, volume.Name, FSType)
+			volume.ISCSI.FSType = VolumeTemplateForElement(volume.Name, FSType)
 			volume.ISCSI.ISCSIInterface = VolumeTemplateForElement(volume.Name, ISCSIInterface)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.RBD != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[FSType] = volume.RBD.FSType
@@ -253,7 +525,7 @@ func generateTemplateForVolume(volumes []apiv1.Volume, key string, value map[str
 			volume.RBD.RBDPool = VolumeTemplateForElement(volume.Name, RBDPool)
 			volume.RBD.RadosUser = VolumeTemplateForElement(volume.Name, RadosUser)
 			volume.RBD.Keyring = VolumeTemplateForElement(volume.Name, Keyring)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.Quobyte != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[Registry] = volume.Quobyte.Registry
@@ -264,7 +536,7 @@ func generateTemplateForVolume(volumes []apiv1.Volume, key string, value map[str
 			volume.Quobyte.Volume = VolumeTemplateForElement(volume.Name, Volume)
 			volume.Quobyte.Group = VolumeTemplateForElement(volume.Name, Group)
 			volume.Quobyte.User = VolumeTemplateForElement(volume.Name, User)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.FlexVolume != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap["Driver"] = volume.FlexVolume.Driver
@@ -272,14 +544,14 @@ func generateTemplateForVolume(volumes []apiv1.Volume, key string, value map[str
 			// TODO secret reference
 			volume.FlexVolume.Driver = VolumeTemplateForElement(volume.Name, "Driver")
 			volume.FlexVolume.FSType = VolumeTemplateForElement(volume.Name, FSType)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.Cinder != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[FSType] = volume.Cinder.FSType
 			volumeMap[VolumeID] = volume.Cinder.VolumeID
 			volume.Cinder.FSType = VolumeTemplateForElement(volume.Name, FSType)
 			volume.Cinder.VolumeID = VolumeTemplateForElement(volume.Name, VolumeID)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.CephFS != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[Path] = volume.CephFS.Path
@@ -288,26 +560,26 @@ func generateTemplateForVolume(volumes []apiv1.Volume, key string, value map[str
 			volume.CephFS.Path = VolumeTemplateForElement(volume.Name, Path)
 			volume.CephFS.SecretFile = VolumeTemplateForElement(volume.Name, SecretFile)
 			volume.CephFS.User = VolumeTemplateForElement(volume.Name, User)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.Flocker != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[DatasetName] = volume.Flocker.DatasetName
 			volume.Flocker.DatasetName = VolumeTemplateForElement(volume.Name, DatasetName)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.DownwardAPI != nil {
 			// TODO
 		} else if volume.FC != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[FSType] = volume.FC.FSType
 			volume.FC.FSType = VolumeTemplateForElement(volume.Name, FSType)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.AzureFile != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[SecretName] = volume.AzureFile.SecretName
 			volumeMap[ShareName] = volume.AzureFile.ShareName
 			volume.AzureFile.ShareName = VolumeTemplateForElement(volume.Name, ShareName)
 			volume.AzureFile.SecretName = VolumeTemplateForElement(volume.Name, SecretName)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.AzureDisk != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[DiskName] = volume.AzureDisk.DiskName
@@ -316,30 +588,33 @@ func generateTemplateForVolume(volumes []apiv1.Volume, key string, value map[str
 			volume.AzureDisk.DiskName = VolumeTemplateForElement(volume.Name, DiskName)
 			volume.AzureDisk.DataDiskURI = VolumeTemplateForElement(volume.Name, DataDiskURI)
 			// volume.AzureDisk.FSType = *string(VolumeTemplateForElement(volume.Name, "FSType"))
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		} else if volume.VsphereVolume != nil {
 			ifCondition = buildIfConditionForVolume(volume.Name)
 			volumeMap[FSType] = volume.VsphereVolume.FSType
 			volumeMap[VolumePath] = volume.VsphereVolume.VolumePath
 			volume.VsphereVolume.FSType = VolumeTemplateForElement(volume.Name, FSType)
 			volume.VsphereVolume.VolumePath = VolumeTemplateForElement(volume.Name, VolumePath)
-			persistence[volume.Name] = volumeMap
+			persistence[generateSafeKey(volume.Name)] = volumeMap
 		}
-		volumeData, err := yaml.Marshal(vol)
+		volumeNode, err := toTemplateNode(volume)
 		if err != nil {
-			log.Fatal(err)
+			return nil, nil, fmt.Errorf("volume %q: %v", volume.Name, err)
 		}
 		if len(ifCondition) != 0 {
-			partialvolumeTemplate = partialVolumeTemplate(string(volumeData), ifCondition)
+			volumeTemplate = append(volumeTemplate, ifBlock{
+				Cond: ifCondition,
+				Then: volumeNode,
+				Else: map[string]interface{}{"name": volume.Name, "emptyDir": map[string]interface{}{}},
+			})
 		} else {
-			partialvolumeTemplate = string(volumeData)
+			volumeTemplate = append(volumeTemplate, volumeNode)
 		}
-		volumeTemplate = volumeTemplate + partialvolumeTemplate
 	}
-	return volumeTemplate, persistence
+	return volumeTemplate, persistence, nil
 }
 
-func generateTemplateForContainer(containers []apiv1.Container, key string, value map[string]interface{}) []apiv1.Container {
+func generateTemplateForContainer(containers []apiv1.Container, key string, value map[string]interface{}) ([]apiv1.Container, error) {
 	result := make([]apiv1.Container, len(containers))
 	for i, container := range containers {
 		containterValue := make(map[string]interface{}, 0)
@@ -349,61 +624,69 @@ func generateTemplateForContainer(containers []apiv1.Container, key string, valu
 			containterValue[ImagePullPolicy] = string(container.ImagePullPolicy)
 			container.ImagePullPolicy = apiv1.PullPolicy(addContainerValue(key, containerName, ImagePullPolicy))
 		}
-		if len(container.Env) != 0 {
-			for k, v := range container.Env {
-				envName := generateSafeKey(v.Name)
-				if len(v.Value) != 0 {
-					containterValue[envName] = v.Value
-				}
-				if v.ValueFrom != nil {
-					if v.ValueFrom.ConfigMapKeyRef != nil {
-						if checkIfNameExist(v.ValueFrom.ConfigMapKeyRef.Name, "Configmap") {
-							container.Env[k].ValueFrom.ConfigMapKeyRef.Name = fmt.Sprintf(`{{ template "fullname" . }}-%s`, v.ValueFrom.ConfigMapKeyRef.Name)
-							containterValue[envName] = v.ValueFrom.ConfigMapKeyRef.Key
-						}
-					} else if v.ValueFrom.SecretKeyRef != nil {
-						if checkIfNameExist(v.ValueFrom.SecretKeyRef.Name, "Secret") {
-							container.Env[k].ValueFrom.SecretKeyRef.Name = fmt.Sprintf(`{{ template "fullname" . }}-%s`, v.ValueFrom.SecretKeyRef.Name)
-							containterValue[envName] = v.ValueFrom.SecretKeyRef.Key
-						}
-					}
+		env := make(map[string]interface{}, 0)
+		for k, v := range container.Env {
+			if v.ValueFrom != nil {
+				if v.ValueFrom.ConfigMapKeyRef != nil && checkIfNameExist(v.ValueFrom.ConfigMapKeyRef.Name, "Configmap") {
+					container.Env[k].ValueFrom.ConfigMapKeyRef.Name = fmt.Sprintf("%s-%s", includeHelper("fullname"), v.ValueFrom.ConfigMapKeyRef.Name)
+				} else if v.ValueFrom.SecretKeyRef != nil && checkIfNameExist(v.ValueFrom.SecretKeyRef.Name, "Secret") {
+					container.Env[k].ValueFrom.SecretKeyRef.Name = fmt.Sprintf("%s-%s", includeHelper("fullname"), v.ValueFrom.SecretKeyRef.Name)
 				}
-				container.Env[k].Value = fmt.Sprintf("{{.Values.%s.%s.%s}}", key, generateSafeKey(container.Name), envName)
+				continue
 			}
+			// env values get their own map, so they can't collide with the
+			// other values of the container
+			envName := generateSafeKey(v.Name)
+			env[envName] = v.Value
+			container.Env[k].Value = fmt.Sprintf("{{.Values.%s.%s.%s.%s}}", key, containerName, Env, envName)
+		}
+		if len(env) != 0 {
+			containterValue[Env] = env
+		}
+
+		resources, err := toTemplateNode(container.Resources)
+		if err != nil {
+			return nil, err
+		}
+		containterValue[Resources] = resources
+		container.Resources = apiv1.ResourceRequirements{}
+		securityContext, err := toTemplateNode(container.SecurityContext)
+		if err != nil {
+			return nil, err
+		}
+		if securityContext == nil {
+			securityContext = map[string]interface{}{}
+		}
+		containterValue[SecurityContext] = securityContext
+		for name, probe := range containerProbes(container) {
+			if probe == nil {
+				continue
+			}
+			node, err := toTemplateNode(probe)
+			if err != nil {
+				return nil, err
+			}
+			probeValue := node.(map[string]interface{})
+			probeValue[Enabled] = true
+			containterValue[name] = probeValue
 		}
 
 		result[i] = container
 		value[generateSafeKey(container.Name)] = containterValue
 	}
-	return result
+	return result, nil
 }
 
-func generateTemplateForLables(labels map[string]string) map[string]string { // Add labels needed for chart
+// generateTemplateForLables drops the labels that the labels helper template
+// sets, which is merged into the labels in setObjectMetaInTemplate.
+func generateTemplateForLables(labels map[string]string) map[string]string {
 	if labels == nil {
 		labels = make(map[string]string, 0)
 	}
-	labels["chart"] = `{{.Chart.Name}}-{{.Chart.Version}}`
-	labels["release"] = `{{.Release.Name}}`
-	labels["heritage"] = `{{.Release.Service}}`
-	return labels
-}
-
-func partialVolumeTemplate(data string, ifCondition string) string {
-	volumeElse := `{{- else }}
-  emptyDir: {}
-{{- end }}
-`
-	templateData := ""
-	str := strings.Split(data, "\n")
-	templateData = templateData + ifCondition + "\n"
-	for _, l := range str {
-		if len(l) == 0 {
-			continue
-		}
-		templateData = templateData + l + "\n"
+	for _, l := range recommendedLabels {
+		delete(labels, l)
 	}
-	templateData = templateData + volumeElse
-	return templateData
+	return labels
 }
 
 func SaveChartfile(filename string, cf *chart.Metadata) error {
@@ -433,37 +716,32 @@ func addTemplateImageValue(containerName string, image string, key string, conta
 		containerValue[Image] = image
 		containerValue[ImageTag] = "latest"
 	}
-	key = generateSafeKey(key)
 	imageNameTemplate := fmt.Sprintf("{{.Values.%s.%s.%s}}", key, containerName, Image)
 	imageTagTemplate := fmt.Sprintf("{{.Values.%s.%s.%s}}", key, containerName, ImageTag)
 	imageTemplate := fmt.Sprintf("%s:%s", imageNameTemplate, imageTagTemplate)
 	return imageTemplate
 }
 
-func addVolumeToTemplateForPod(templatePod string, templatevolumes string) string {
-	templatevolumes = makeSpaceForVolume(templatevolumes, "  ")
-	template := addVolumeInPodTemplate(templatePod, templatevolumes)
-	return template
-}
-
-func removeEmptyFields(temp string) string {
+func removeEmptyFields(temp string) (string, error) {
 	var resource map[string]interface{}
 	err := yaml.Unmarshal([]byte(temp), &resource)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 	delete(resource, "status")
 	for k, v := range resource {
-		omitEmptyMap(resource, k, v)
+		if err := omitEmptyMap(resource, k, v); err != nil {
+			return "", err
+		}
 	}
 	yamlData, err := yaml.Marshal(resource)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
-	return string(yamlData)
+	return string(yamlData), nil
 }
 
-func omitEmptyMap(mp map[string]interface{}, k string, v interface{}) {
+func omitEmptyMap(mp map[string]interface{}, k string, v interface{}) error {
 	if reflect.ValueOf(v).Kind() == reflect.Ptr {
 		v = reflect.ValueOf(v).Elem()
 	}
@@ -476,21 +754,30 @@ func omitEmptyMap(mp map[string]interface{}, k string, v interface{}) {
 		if err == nil {
 			var newMap map[string]interface{}
 			if err := json.Unmarshal(data, &newMap); err != nil {
-				log.Fatal(err)
+				return fmt.Errorf("%s: %v", k, err)
 			}
 			for k1, val1 := range newMap {
-				omitEmptyMap(newMap, k1, val1)
+				if err := omitEmptyMap(newMap, k1, val1); err != nil {
+					return fmt.Errorf("%s.%v", k, err)
+				}
 			}
 			mp[k] = newMap
 		}
 	} else if reflect.ValueOf(v).Kind() == reflect.Slice {
-		mp[k] = omitEmptySlice(InterfaceToSlice(v))
+		slice, err := InterfaceToSlice(v)
+		if err != nil {
+			return fmt.Errorf("%s: %v", k, err)
+		}
+		if mp[k], err = omitEmptySlice(slice); err != nil {
+			return fmt.Errorf("%s%v", k, err)
+		}
 	}
+	return nil
 }
 
-func omitEmptySlice(i []interface{}) []interface{} {
+func omitEmptySlice(i []interface{}) ([]interface{}, error) {
 	var z []interface{}
-	for _, v := range i {
+	for idx, v := range i {
 		if reflect.ValueOf(v).Kind() == reflect.Ptr {
 			v = reflect.ValueOf(v).Elem()
 		}
@@ -499,31 +786,40 @@ func omitEmptySlice(i []interface{}) []interface{} {
 		} else if reflect.ValueOf(v).Kind() == reflect.Map || reflect.ValueOf(v).Kind() == reflect.Struct {
 			data, err := json.Marshal(reflect.ValueOf(v).Interface())
 			if err != nil {
-				log.Fatal(err)
+				return nil, fmt.Errorf("[%d]: %v", idx, err)
 			}
 			var newMap map[string]interface{}
 			if err := json.Unmarshal(data, &newMap); err != nil {
-				log.Fatal(err)
+				return nil, fmt.Errorf("[%d]: %v", idx, err)
 			}
 			for k1, val1 := range newMap {
-				omitEmptyMap(newMap, k1, val1)
+				if err := omitEmptyMap(newMap, k1, val1); err != nil {
+					return nil, fmt.Errorf("[%d].%v", idx, err)
+				}
 			}
 			z = append(z, newMap)
 		} else if reflect.ValueOf(v).Kind() == reflect.Slice {
-			val1 := omitEmptySlice(InterfaceToSlice(v))
+			slice, err := InterfaceToSlice(v)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", idx, err)
+			}
+			val1, err := omitEmptySlice(slice)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]%v", idx, err)
+			}
 			z = append(z, val1)
 
 		} else {
 			z = append(z, v)
 		}
 	}
-	return z
+	return z, nil
 }
 
-func InterfaceToSlice(slice interface{}) []interface{} {
+func InterfaceToSlice(slice interface{}) ([]interface{}, error) {
 	s := reflect.ValueOf(slice)
 	if s.Kind() != reflect.Slice {
-		log.Fatal("Not slice")
+		return nil, fmt.Errorf("%T is not a slice", slice)
 	}
 
 	ret := make([]interface{}, s.Len())
@@ -532,7 +828,7 @@ func InterfaceToSlice(slice interface{}) []interface{} {
 		ret[i] = s.Index(i).Interface()
 	}
 
-	return ret
+	return ret, nil
 }
 
 func isEmptyValue(v reflect.Value) bool {
@@ -553,57 +849,6 @@ func isEmptyValue(v reflect.Value) bool {
 	return false
 }
 
-func makeSpaceForVolume(templatevolumes string, space string) string {
-	s := space + "volumes:\n"
-	str := strings.Split(templatevolumes, "\n")
-	for _, l := range str {
-		if len(l) == 0 {
-			continue
-		}
-		s = s + space + l + "\n"
-	}
-	return s
-}
-
-func addVolumeInPodTemplate(pod string, volume string) string {
-	str := strings.Split(pod, "\n")
-	templateForPod := ""
-	for _, l := range str {
-		if len(l) == 0 {
-			continue
-		}
-		if strings.HasPrefix("spec:", l) {
-			templateForPod = templateForPod + l + "\n" + volume
-		} else {
-			templateForPod = templateForPod + l + "\n"
-		}
-	}
-	return templateForPod
-}
-
-func addVolumeToTemplate(rc string, volumes string) string {
-	volumes = makeSpaceForVolume(volumes, "      ")
-	template := addVolumeInRcTemplate(rc, volumes)
-	return template
-}
-
-func addVolumeInRcTemplate(rc string, volumes string) string {
-	str := strings.Split(rc, "\n")
-	templateForPod := ""
-	for _, l := range str {
-		if len(l) == 0 {
-			continue
-		}
-		if strings.HasPrefix("    spec:", l) {
-			templateForPod = templateForPod + l + "\n" + volumes
-		} else {
-			templateForPod = templateForPod + l + "\n"
-		}
-
-	}
-	return templateForPod
-}
-
 func generateServiceSpecTemplate(svc apiv1.ServiceSpec, key string, value map[string]interface{}) apiv1.ServiceSpec {
 	if len(svc.ClusterIP) != 0 {
 		value[ClusterIP] = svc.ClusterIP
@@ -628,6 +873,145 @@ func generateServiceSpecTemplate(svc apiv1.ServiceSpec, key string, value map[st
 	return svc
 }
 
+// generateIngressSpecTemplate moves the rules and TLS entries of spec to the
+// hosts and tls lists of value, see setIngressRulesInTemplate.
+func generateIngressSpecTemplate(spec networking.IngressSpec, key string, value map[string]interface{}) (networking.IngressSpec, error) {
+	if spec.IngressClassName != nil {
+		value[IngressClassName] = *spec.IngressClassName
+		className := fmt.Sprintf("{{.Values.%s.%s}}", key, IngressClassName)
+		spec.IngressClassName = &className
+	}
+	if spec.DefaultBackend != nil {
+		generateIngressBackendTemplate(spec.DefaultBackend)
+	}
+	if len(spec.Rules) != 0 {
+		hosts := make([]interface{}, 0, len(spec.Rules))
+		for _, rule := range spec.Rules {
+			hostValue := map[string]interface{}{"host": rule.Host}
+			if rule.HTTP != nil {
+				paths, err := toTemplateNode(rule.HTTP.Paths)
+				if err != nil {
+					return spec, err
+				}
+				hostValue[Paths] = paths
+			}
+			hosts = append(hosts, hostValue)
+		}
+		value[Hosts] = hosts
+		spec.Rules = nil
+	}
+	if len(spec.TLS) != 0 {
+		tls, err := toTemplateNode(spec.TLS)
+		if err != nil {
+			return spec, err
+		}
+		value[TLS] = tls
+		spec.TLS = nil
+	}
+	return spec, nil
+}
+
+// setIngressRulesInTemplate renders a rule for every entry of the hosts
+// values of an Ingress, and a TLS entry for every entry of its tls values.
+func setIngressRulesInTemplate(tw *templateWriter, key string, value map[string]interface{}) error {
+	if hosts, ok := value[Hosts].([]interface{}); ok {
+		path := map[string]interface{}{
+			"backend": map[string]interface{}{
+				"resource": withBlock{Value: ".backend.resource", Then: map[string]interface{}{
+					"apiGroup": withBlock{Value: ".apiGroup", Then: "{{ . }}"},
+					"kind":     "{{ .kind }}",
+					"name":     "{{ .name }}",
+				}},
+				"service": withBlock{Value: ".backend.service", Then: map[string]interface{}{
+					"name": rawExpr(ingressServiceName(hosts)),
+					"port": map[string]interface{}{
+						"name":   withBlock{Value: ".port.name", Then: "{{ . }}"},
+						"number": withBlock{Value: ".port.number", Then: rawExpr("{{ . }}")},
+					},
+				}},
+			},
+			"path":     withBlock{Value: ".path", Then: "{{ . }}"},
+			"pathType": withBlock{Value: ".pathType", Then: "{{ . }}"},
+		}
+		rule := map[string]interface{}{
+			"host": "{{ .host }}",
+			"http": withBlock{Value: "." + Paths, Then: map[string]interface{}{
+				Paths: []interface{}{rangeBlock{Value: ".", Then: path}},
+			}},
+		}
+		hostsValue := fmt.Sprintf(".Values.%s.%s", key, Hosts)
+		if err := tw.Set([]interface{}{rangeBlock{Value: hostsValue, Then: rule}}, "spec", "rules"); err != nil {
+			return err
+		}
+	}
+	if _, ok := value[TLS]; ok {
+		tls := map[string]interface{}{
+			Hosts:      []interface{}{rangeBlock{Value: "." + Hosts, Then: "{{ . }}"}},
+			SecretName: withBlock{Value: "." + SecretName, Then: "{{ . }}"},
+		}
+		tlsValue := fmt.Sprintf(".Values.%s.%s", key, TLS)
+		if err := tw.Set([]interface{}{rangeBlock{Value: tlsValue, Then: tls}}, "spec", "tls"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingressServiceName returns the template of a backend service name, in the
+// scope of the backend, that prefixes the Services of the chart referenced by
+// hosts with the fullname of the release.
+func ingressServiceName(hosts []interface{}) string {
+	var names []string
+	for _, h := range hosts {
+		paths, _ := h.(map[string]interface{})[Paths].([]interface{})
+		for _, p := range paths {
+			service, _ := p.(map[string]interface{})["backend"].(map[string]interface{})["service"].(map[string]interface{})
+			name, _ := service["name"].(string)
+			if !PreserveName && checkIfNameExist(name, "Service") {
+				names = append(names, strconv.Quote(name))
+			}
+		}
+	}
+	if len(names) == 0 {
+		return "{{ .name }}"
+	}
+	fullname := fmt.Sprintf(`{{ include %q $ }}`, helperName("fullname"))
+	return fmt.Sprintf("{{ if has .name (list %s) }}%s-{{ end }}{{ .name }}", strings.Join(names, " "), fullname)
+}
+
+// generateTemplateForSubjects points ServiceAccount subjects at the chart's
+// own accounts when they are part of the chart.
+func generateTemplateForSubjects(subjects []rbac.Subject) []rbac.Subject {
+	if PreserveName {
+		return subjects
+	}
+	for i, s := range subjects {
+		if s.Kind == rbac.ServiceAccountKind && checkIfNameExist(s.Name, "ServiceAccount") {
+			subjects[i].Namespace = fmt.Sprintf("{{ default .Release.Namespace .Values.%s.%s }}", valueKey(s.Name, "serviceaccount"), Namespace)
+			subjects[i].Name = fmt.Sprintf("%s-%s", includeHelper("fullname"), s.Name)
+		}
+	}
+	return subjects
+}
+
+func generateTemplateForRoleRef(roleRef rbac.RoleRef) rbac.RoleRef {
+	if !PreserveName && checkIfNameExist(roleRef.Name, roleRef.Kind) {
+		roleRef.Name = fmt.Sprintf("%s-%s", includeHelper("fullname"), roleRef.Name)
+	}
+	return roleRef
+}
+
+// generateIngressBackendTemplate points the backend at the chart's own
+// service when the referenced Service is part of the chart.
+func generateIngressBackendTemplate(backend *networking.IngressBackend) {
+	if backend.Service == nil || PreserveName {
+		return
+	}
+	if checkIfNameExist(backend.Service.Name, "Service") {
+		backend.Service.Name = fmt.Sprintf("%s-%s", includeHelper("fullname"), backend.Service.Name)
+	}
+}
+
 func generatePersistentVolumeClaimSpec(pvcspec apiv1.PersistentVolumeClaimSpec, key string, value map[string]interface{}) apiv1.PersistentVolumeClaimSpec {
 	if len(pvcspec.VolumeName) != 0 {
 		value[VolumeName] = pvcspec.VolumeName
@@ -676,12 +1060,21 @@ func generateSafeKey(name string) string {
 	return key
 }
 
+// valueKey returns the key the values of an object are kept under: the
+// object name, then the suffix of its template, so objects of different
+// kinds can share a name without sharing values.
+func valueKey(name string, suffix string) string {
+	return generateSafeKey(name) + "." + generateSafeKey(suffix)
+}
+
+// VolumeTemplateForElement returns the template of a field of a volume, kept
+// under persistence in values.
 func VolumeTemplateForElement(volumeName string, element string) string {
-	return fmt.Sprintf(`{{.Values.%s.%s}}`, volumeName, element)
+	return fmt.Sprintf(`{{.Values.%s.%s.%s}}`, Persistence, generateSafeKey(volumeName), element)
 }
 
 func buildIfConditionForVolume(volumeName string) string {
-	return fmt.Sprintf("{{- if .Values.persistence.%s.%s}}", volumeName, Enabled)
+	return fmt.Sprintf(".Values.%s.%s.%s", Persistence, generateSafeKey(volumeName), Enabled)
 }
 
 func checkIfNameExist(name string, objType string) bool {
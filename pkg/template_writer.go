@@ -0,0 +1,373 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// templateWriter renders a Kubernetes object as a Helm template. Generators
+// set paths of the object to template expressions and conditional blocks
+// instead of splicing text into the marshalled YAML, so the output does not
+// depend on indentation or on what other fields happen to be called.
+type templateWriter struct {
+	object map[string]interface{}
+}
+
+// rawExpr is a template expression rendered as is. Use it for values that
+// must not be quoted, like numbers and booleans.
+type rawExpr string
+
+// yamlExpr is a template pipeline, without delimiters, whose value is
+// rendered as YAML with toYaml and indented under its key.
+type yamlExpr string
+
+// ifBlock renders a map entry or list item inside {{- if Cond }}, with an
+// optional {{- else }} branch.
+type ifBlock struct {
+	Cond string
+	Then interface{}
+	Else interface{}
+}
+
+// mergeEntries is a template pipeline whose value, a map, is rendered as
+// entries of the map it is merged into.
+type mergeEntries string
+
+// includeEntries is the name of a helper template whose output, map
+// entries, is included in the map it is merged into.
+type includeEntries string
+
+// withBlock renders a map entry or list item inside {{- with Value }}, so it
+// is left out when Value is empty. Then refers to the value as ".".
+type withBlock struct {
+	Value string
+	Then  interface{}
+}
+
+// rangeBlock renders a list item once for every item of Value, inside
+// {{- range Value }}. Then refers to the item as ".".
+type rangeBlock struct {
+	Value string
+	Then  interface{}
+}
+
+// newTemplateWriter starts a template from obj with empty fields removed.
+func newTemplateWriter(obj interface{}) (*templateWriter, error) {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	temp, err := removeEmptyFields(string(data))
+	if err != nil {
+		return nil, err
+	}
+	object := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(temp), &object); err != nil {
+		return nil, err
+	}
+	return &templateWriter{object: object}, nil
+}
+
+// toTemplateNode converts obj into a node that can be set in a template.
+func toTemplateNode(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var node interface{}
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// Set replaces the value at path, creating missing maps on the way. List
+// items are addressed by their index.
+func (w *templateWriter) Set(value interface{}, path ...string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("empty path")
+	}
+	parent, err := w.nodeAt(path[:len(path)-1]...)
+	if err != nil {
+		return err
+	}
+	last := path[len(path)-1]
+	switch n := parent.(type) {
+	case map[string]interface{}:
+		n[last] = value
+	case []interface{}:
+		idx, err := listIndex(n, last)
+		if err != nil {
+			return fmt.Errorf("%s: %v", strings.Join(path[:len(path)-1], "."), err)
+		}
+		n[idx] = value
+	default:
+		return fmt.Errorf("%s: not a map or list", strings.Join(path[:len(path)-1], "."))
+	}
+	return nil
+}
+
+// nodeAt returns the node at path, creating missing maps on the way.
+func (w *templateWriter) nodeAt(path ...string) (interface{}, error) {
+	var node interface{} = w.object
+	for i, p := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			if _, found := n[p]; !found {
+				n[p] = make(map[string]interface{})
+			}
+			node = n[p]
+		case []interface{}:
+			idx, err := listIndex(n, p)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", strings.Join(path[:i], "."), err)
+			}
+			node = n[idx]
+		default:
+			return nil, fmt.Errorf("%s: not a map or list", strings.Join(path[:i], "."))
+		}
+	}
+	return node, nil
+}
+
+func listIndex(list []interface{}, p string) (int, error) {
+	idx, err := strconv.Atoi(p)
+	if err != nil || idx < 0 || idx >= len(list) {
+		return 0, fmt.Errorf("no list item %s", p)
+	}
+	return idx, nil
+}
+
+// SetRaw sets path to a template expression that is rendered unquoted.
+func (w *templateWriter) SetRaw(expr string, path ...string) error {
+	return w.Set(rawExpr(expr), path...)
+}
+
+// SetYaml sets path to a template pipeline, like .Values.app.resources,
+// whose value is rendered as a YAML block.
+func (w *templateWriter) SetYaml(expr string, path ...string) error {
+	return w.Set(yamlExpr(expr), path...)
+}
+
+// Merge adds the entries of the map that pipeline evaluates to, like
+// .Values.commonLabels, to the map at path. Nothing is added when the map is
+// empty.
+func (w *templateWriter) Merge(pipeline string, path ...string) error {
+	return w.merge(mergeEntries(pipeline), path...)
+}
+
+// MergeInclude adds the entries that the named helper template renders to
+// the map at path.
+func (w *templateWriter) MergeInclude(name string, path ...string) error {
+	return w.merge(includeEntries(name), path...)
+}
+
+func (w *templateWriter) merge(entries interface{}, path ...string) error {
+	node, err := w.nodeAt(path...)
+	if err != nil {
+		return err
+	}
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: not a map", strings.Join(path, "."))
+	}
+	n := 0
+	for k := range m {
+		if strings.HasPrefix(k, mergeKeyPrefix) {
+			n++
+		}
+	}
+	// merged entries keep the order they were added in
+	m[fmt.Sprintf("%s%02d__", mergeKeyPrefix, n)] = entries
+	return nil
+}
+
+const mergeKeyPrefix = "__chartify_merge_"
+
+// Render returns the template. Map keys are sorted, so the output is
+// deterministic.
+func (w *templateWriter) Render() (string, error) {
+	r := &templateRenderer{nodes: make(map[string]interface{})}
+	return r.render(w.object)
+}
+
+// templateRenderer marshals a node tree with rawExpr and ifBlock nodes
+// replaced by placeholders, and then expands the placeholders line by line.
+type templateRenderer struct {
+	nodes map[string]interface{}
+}
+
+func (r *templateRenderer) placeholder(node interface{}) string {
+	p := fmt.Sprintf("__chartify_%d__", len(r.nodes))
+	r.nodes[p] = node
+	return p
+}
+
+func (r *templateRenderer) replace(node interface{}) interface{} {
+	switch n := node.(type) {
+	case rawExpr, yamlExpr, mergeEntries, includeEntries, ifBlock, withBlock, rangeBlock:
+		return r.placeholder(n)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			m[k] = r.replace(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(n))
+		for i, v := range n {
+			s[i] = r.replace(v)
+		}
+		return s
+	}
+	return node
+}
+
+func (r *templateRenderer) render(node interface{}) (string, error) {
+	data, err := yaml.Marshal(r.replace(node))
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		expanded, err := r.expandLine(line)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(expanded)
+	}
+	return out.String(), nil
+}
+
+func (r *templateRenderer) expandLine(line string) (string, error) {
+	placeholders := make([]string, 0)
+	for p := range r.nodes {
+		if strings.Contains(line, p) {
+			placeholders = append(placeholders, p)
+		}
+	}
+	sort.Strings(placeholders)
+	for _, p := range placeholders {
+		switch n := r.nodes[p].(type) {
+		case rawExpr:
+			line = strings.Replace(line, p, string(n), 1)
+		case yamlExpr:
+			if !strings.HasSuffix(line, p) {
+				return "", fmt.Errorf("yaml expression must be a map entry value: %q", line)
+			}
+			prefix := strings.TrimSuffix(line, p)
+			return prefix + n.render(len(prefix)-len(strings.TrimLeft(prefix, " -"))), nil
+		case ifBlock:
+			if !strings.HasSuffix(line, p) {
+				return "", fmt.Errorf("conditional block must be a map entry or list item: %q", line)
+			}
+			return r.expandBlock(strings.TrimSuffix(line, p), "if", n)
+		case mergeEntries, includeEntries:
+			prefix := strings.TrimSuffix(line, p)
+			indent := prefix[:len(prefix)-len(strings.TrimLeft(prefix, " "))]
+			if !strings.HasPrefix(strings.TrimPrefix(prefix, indent), mergeKeyPrefix) {
+				return "", fmt.Errorf("merged entries must be in a map: %q", line)
+			}
+			if name, ok := n.(includeEntries); ok {
+				return fmt.Sprintf("%s{{- include %q . | nindent %d }}\n", indent, string(name), len(indent)), nil
+			}
+			return fmt.Sprintf("%s{{- with %s }}\n%s{{- toYaml . | nindent %d }}\n%s{{- end }}\n",
+				indent, n, indent, len(indent), indent), nil
+		case withBlock:
+			if !strings.HasSuffix(line, p) {
+				return "", fmt.Errorf("with block must be a map entry or list item: %q", line)
+			}
+			return r.expandBlock(strings.TrimSuffix(line, p), "with", ifBlock{Cond: n.Value, Then: n.Then})
+		case rangeBlock:
+			prefix := strings.TrimSuffix(line, p)
+			if !strings.HasSuffix(line, p) || !strings.HasSuffix(prefix, "- ") {
+				return "", fmt.Errorf("range block must be a list item: %q", line)
+			}
+			return r.expandBlock(prefix, "range", ifBlock{Cond: n.Value, Then: n.Then})
+		}
+	}
+	return line + "\n", nil
+}
+
+// expandBlock renders a block that was marshalled as prefix+placeholder,
+// where prefix is the indentation followed by "- " for a list item or by
+// "key: " for a map entry. action is the template action of the block.
+func (r *templateRenderer) expandBlock(prefix string, action string, b ifBlock) (string, error) {
+	indent := prefix[:len(prefix)-len(strings.TrimLeft(prefix, " "))]
+	entry := strings.TrimPrefix(prefix, indent)
+	var out strings.Builder
+	if entry != "- " && strings.HasPrefix(entry, "- ") {
+		// first key of a map inside a list
+		out.WriteString(indent + "-\n")
+		indent += "  "
+		entry = strings.TrimPrefix(entry, "- ")
+	}
+	branch := func(v interface{}) error {
+		var s string
+		var err error
+		if e, ok := v.(yamlExpr); ok && entry != "- " {
+			out.WriteString(indent + entry + e.render(len(indent)))
+			return nil
+		}
+		if entry == "- " {
+			s, err = r.render([]interface{}{v})
+		} else {
+			s, err = r.renderEntry(strings.TrimSuffix(entry, " "), v)
+		}
+		if err != nil {
+			return err
+		}
+		out.WriteString(indentLines(s, indent))
+		return nil
+	}
+	out.WriteString(fmt.Sprintf("%s{{- %s %s }}\n", indent, action, b.Cond))
+	if err := branch(b.Then); err != nil {
+		return "", err
+	}
+	if b.Else != nil {
+		out.WriteString(indent + "{{- else }}\n")
+		if err := branch(b.Else); err != nil {
+			return "", err
+		}
+	}
+	out.WriteString(indent + "{{- end }}\n")
+	return out.String(), nil
+}
+
+// render returns the expression for a map entry whose key starts at column
+// keyIndent.
+func (e yamlExpr) render(keyIndent int) string {
+	expr := string(e)
+	if strings.ContainsAny(expr, " |") {
+		expr = "(" + expr + ")"
+	}
+	return fmt.Sprintf("{{- toYaml %s | nindent %d }}\n", expr, keyIndent+2)
+}
+
+// renderEntry renders "key: v" where key is already formatted as YAML.
+func (r *templateRenderer) renderEntry(key string, v interface{}) (string, error) {
+	s, err := r.render(v)
+	if err != nil {
+		return "", err
+	}
+	switch v.(type) {
+	case map[string]interface{}:
+		return key + "\n" + indentLines(s, "  "), nil
+	case []interface{}:
+		return key + "\n" + s, nil
+	}
+	return key + " " + s, nil
+}
+
+func indentLines(s string, indent string) string {
+	var out strings.Builder
+	for _, l := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		out.WriteString(indent + l + "\n")
+	}
+	return out.String()
+}
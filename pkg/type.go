@@ -7,28 +7,81 @@ const (
 	ChartfileName = "Chart.yaml"
 	// ValuesfileName is the default values file name.
 	ValuesfileName = "values.yaml"
+	// SchemafileName is the name of the JSON schema of the values file.
+	SchemafileName = "values.schema.json"
 	// TemplatesDir is the relative directory name for templates.
 	TemplatesDir = "templates"
-	// HelpersName is the name of the example NOTES.txt file.
+	// CRDsDir is the relative directory name for CustomResourceDefinitions.
+	CRDsDir = "crds"
+	// HelpersName is the name of the helper templates file.
 	HelpersName = "_helpers.tpl"
+	// NotesName is the name of the file printed after installing the chart.
+	NotesName = "NOTES.txt"
+	// GeneratedDir is the relative directory name for the files of a chart as
+	// chartify last generated them, the base of chartify update.
+	GeneratedDir = ".chartify"
+	// ConflictSuffix is appended to the name of a file edited in a chart to
+	// write the version chartify update would have replaced it with.
+	ConflictSuffix = ".chartify-new"
+	// HelmignoreName is the name of the file listing what Helm leaves out of
+	// a chart.
+	HelmignoreName = ".helmignore"
 )
 
+// defaultHelpers are the helper templates of a chart, with <CHARTNAME>
+// standing in for the name of the chart.
 const defaultHelpers = `{{/* vim: set filetype=mustache: */}}
 {{/*
 Expand the name of the chart.
 */}}
-{{- define "name" -}}
-{{- default .Chart.Name .Values.nameOverride | trunc 24 -}}
-{{- end -}}
+{{- define "<CHARTNAME>.name" -}}
+{{- default .Chart.Name .Values.nameOverride | trunc 63 | trimSuffix "-" }}
+{{- end }}
 
 {{/*
 Create a default fully qualified app name.
-We truncate at 24 chars because some Kubernetes name fields are limited to this (by the DNS naming spec).
+We truncate at 63 chars because some Kubernetes name fields are limited to this (by the DNS naming spec).
+If release name contains chart name it will be used as a full name.
 */}}
-{{- define "fullname" -}}
-{{- $name := default .Chart.Name .Values.nameOverride -}}
-{{- printf "%s-%s" .Release.Name $name | trunc 24 -}}
-{{- end -}}
+{{- define "<CHARTNAME>.fullname" -}}
+{{- if .Values.fullnameOverride }}
+{{- .Values.fullnameOverride | trunc 63 | trimSuffix "-" }}
+{{- else }}
+{{- $name := default .Chart.Name .Values.nameOverride }}
+{{- if contains $name .Release.Name }}
+{{- .Release.Name | trunc 63 | trimSuffix "-" }}
+{{- else }}
+{{- printf "%s-%s" .Release.Name $name | trunc 63 | trimSuffix "-" }}
+{{- end }}
+{{- end }}
+{{- end }}
+
+{{/*
+Create chart name and version as used by the chart label.
+*/}}
+{{- define "<CHARTNAME>.chart" -}}
+{{- printf "%s-%s" .Chart.Name .Chart.Version | replace "+" "_" | trunc 63 | trimSuffix "-" }}
+{{- end }}
+
+{{/*
+Common labels
+*/}}
+{{- define "<CHARTNAME>.labels" -}}
+helm.sh/chart: {{ include "<CHARTNAME>.chart" . }}
+{{ include "<CHARTNAME>.selectorLabels" . }}
+{{- if .Chart.AppVersion }}
+app.kubernetes.io/version: {{ .Chart.AppVersion | quote }}
+{{- end }}
+app.kubernetes.io/managed-by: {{ .Release.Service }}
+{{- end }}
+
+{{/*
+Selector labels
+*/}}
+{{- define "<CHARTNAME>.selectorLabels" -}}
+app.kubernetes.io/name: {{ include "<CHARTNAME>.name" . }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+{{- end }}
 `
 
 type valueFileGenerator struct {
@@ -89,9 +142,42 @@ const (
 	Provisioner                    = "provisioner"
 	RestartPolicy                  = "restartPolicy"
 	ReclaimPolicy                  = "reclaimPolicy"
+	Replicas                       = "replicas"
 	MinReplicas                    = "minReplicas"
 	MaxReplicas                    = "maxReplicas"
 	TargetCPUUtilizationPercentage = "targetCPUUtilizationPercentage"
+	IngressClassName               = "ingressClassName"
+	Annotations                    = "annotations"
+	Hosts                          = "hosts"
+	Paths                          = "paths"
+	TLS                            = "tls"
+	Schedule                       = "schedule"
+	Suspend                        = "suspend"
+	ConcurrencyPolicy              = "concurrencyPolicy"
+	StartingDeadlineSeconds        = "startingDeadlineSeconds"
+	SuccessfulJobsHistoryLimit     = "successfulJobsHistoryLimit"
+	FailedJobsHistoryLimit         = "failedJobsHistoryLimit"
+	ServiceAccount                 = "serviceAccount"
+	RBAC                           = "rbac"
+	Create                         = "create"
+	Resources                      = "resources"
+	Env                            = "env"
+	LivenessProbe                  = "livenessProbe"
+	ReadinessProbe                 = "readinessProbe"
+	StartupProbe                   = "startupProbe"
+	NodeSelector                   = "nodeSelector"
+	Tolerations                    = "tolerations"
+	Affinity                       = "affinity"
+	TopologySpreadConstraints      = "topologySpreadConstraints"
+	PriorityClassName              = "priorityClassName"
+	SecurityContext                = "securityContext"
+	PodSecurityContext             = "podSecurityContext"
+	PodAnnotations                 = "podAnnotations"
+	PodLabels                      = "podLabels"
+	CommonLabels                   = "commonLabels"
+	CommonAnnotations              = "commonAnnotations"
+	NameOverride                   = "nameOverride"
+	FullnameOverride               = "fullnameOverride"
 )
 
 func (v *valueFileGenerator) MergeInto(dst map[string]interface{}, key string) {
@@ -106,7 +192,7 @@ func (v *valueFileGenerator) MergeInto(dst map[string]interface{}, key string) {
 		}
 		dst[key] = m
 	} else {
-		fmt.Println("Overwriting string value with map.")
+		fmt.Fprintln(LogOutput, "Overwriting string value with map.")
 		dst[key] = v.value
 		return
 	}
@@ -0,0 +1,334 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	ylib "github.com/ghodss/yaml"
+)
+
+// helmignorePatterns keep the files chartify adds for updates out of the
+// chart Helm installs or packages.
+var helmignorePatterns = []string{GeneratedDir + "/", "*" + ConflictSuffix}
+
+// Update regenerates the chart at Location, keeping what was edited in it
+// since chartify last generated it. values.yaml is merged three ways: values
+// changed in the chart are kept, new values are added and values chartify no
+// longer generates are reported. Other generated files are replaced only when
+// they were not edited; otherwise the new version is written next to them
+// with ConflictSuffix. Chart.yaml is never changed. If there is no chart yet,
+// Update is Create. The chart keeps tracking changes afterwards; when it was
+// created without TrackChanges, there is nothing to merge against the first
+// time, so values set in the chart are kept and every generated file that
+// differs from the chart is written with ConflictSuffix.
+func (g Generator) Update() (string, error) {
+	if g.Output != nil {
+		return "", errors.New("update merges into the chart on disk, it can not be used with Output")
+	}
+	g.TrackChanges = true
+	cdir := filepath.Join(g.Location, g.ChartName)
+	if _, err := os.Stat(filepath.Join(cdir, ChartfileName)); os.IsNotExist(err) {
+		return g.Create()
+	}
+	fmt.Fprintln(LogOutput, "Updating chart...")
+	tmp, err := ioutil.TempDir(os.TempDir(), "chartify")
+	if err != nil {
+		return cdir, err
+	}
+	defer func() {
+		_ = os.RemoveAll(tmp)
+	}()
+	gen := g
+	gen.Location = tmp
+	newDir, generated, skipped, err := gen.generate()
+	if err != nil {
+		return cdir, err
+	}
+	if err := updateChart(cdir, newDir); err != nil {
+		return cdir, err
+	}
+	return cdir, g.finish("UPDATE", cdir, generated, skipped)
+}
+
+// updateChart merges the chart generated in newDir into the chart in
+// chartDir, against the files saved in its GeneratedDir.
+func updateChart(chartDir, newDir string) error {
+	baseDir := filepath.Join(chartDir, GeneratedDir)
+	if err := updateValues(chartDir, baseDir, newDir); err != nil {
+		return err
+	}
+	files, err := generatedFiles(baseDir, newDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		if err := updateFile(name, chartDir, baseDir, newDir); err != nil {
+			return err
+		}
+	}
+	if err := os.RemoveAll(baseDir); err != nil {
+		return err
+	}
+	if err := copyDir(filepath.Join(newDir, GeneratedDir), baseDir); err != nil {
+		return err
+	}
+	return ensureHelmignore(chartDir)
+}
+
+// updateValues writes the three-way merge of the values of the chart.
+func updateValues(chartDir, baseDir, newDir string) error {
+	current, err := readValues(filepath.Join(chartDir, ValuesfileName))
+	if err != nil {
+		return err
+	}
+	base, err := readValues(filepath.Join(baseDir, ValuesfileName))
+	if err != nil {
+		return err
+	}
+	generated, err := readValues(filepath.Join(newDir, ValuesfileName))
+	if err != nil {
+		return err
+	}
+	var removed []string
+	merged := mergeValues("", current, base, generated, &removed)
+	if len(removed) != 0 {
+		sort.Strings(removed)
+		fmt.Fprintln(LogOutput, "No longer generated, kept in values.yaml:")
+		for _, v := range removed {
+			fmt.Fprintln(LogOutput, "  "+v)
+		}
+	}
+	if reflect.DeepEqual(merged, current) {
+		return nil // keep the formatting and comments of the file
+	}
+	data, err := ylib.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(chartDir, ValuesfileName), data, 0o644)
+}
+
+// readValues reads a values file, which may not exist.
+func readValues(filename string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return values, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := ylib.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return values, nil
+}
+
+// mergeValues merges generated, the values chartify generates now, into
+// current, the values of the chart, given base, the values chartify generated
+// before. Values changed or removed in current are kept. The paths of the
+// values of current chartify no longer generates are appended to removed.
+func mergeValues(path string, current, base, generated map[string]interface{}, removed *[]string) map[string]interface{} {
+	merged := make(map[string]interface{}, len(current))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, gv := range generated {
+		cv, inCurrent := current[k]
+		bv, inBase := base[k]
+		cm, currentIsMap := cv.(map[string]interface{})
+		gm, generatedIsMap := gv.(map[string]interface{})
+		switch {
+		case !inCurrent:
+			if !inBase {
+				merged[k] = gv
+			}
+		case currentIsMap && generatedIsMap:
+			bm, _ := bv.(map[string]interface{})
+			merged[k] = mergeValues(path+k+".", cm, bm, gm, removed)
+		case inBase && reflect.DeepEqual(cv, bv):
+			merged[k] = gv
+		}
+	}
+	for k := range base {
+		if _, inGenerated := generated[k]; inGenerated {
+			continue
+		}
+		if _, inCurrent := current[k]; inCurrent {
+			*removed = append(*removed, path+k)
+		}
+	}
+	return merged
+}
+
+// generatedFiles lists the files, other than values.yaml, chartify generated
+// before or generates now, relative to the chart.
+func generatedFiles(baseDir, newDir string) ([]string, error) {
+	names := map[string]bool{}
+	for _, dir := range []string{baseDir, filepath.Join(newDir, GeneratedDir)} {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			name, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			if name != ValuesfileName {
+				names[name] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	files := make([]string, 0, len(names))
+	for name := range names {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// updateFile replaces the file name of the chart with its new version, if it
+// was not edited since chartify generated it.
+func updateFile(name, chartDir, baseDir, newDir string) error {
+	current, err := readOptionalFile(filepath.Join(chartDir, name))
+	if err != nil {
+		return err
+	}
+	base, err := readOptionalFile(filepath.Join(baseDir, name))
+	if err != nil {
+		return err
+	}
+	generated, err := readOptionalFile(filepath.Join(newDir, name))
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(chartDir, name)
+	switch {
+	case bytes.Equal(current, generated):
+		return nil
+	case current == nil && base != nil:
+		fmt.Fprintln(LogOutput, "Deleted in the chart, not recreated:", name)
+		return nil
+	case current == nil || bytes.Equal(current, base):
+		if generated == nil {
+			return os.Remove(filename)
+		}
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filename, generated, 0o644)
+	case generated == nil:
+		fmt.Fprintln(LogOutput, "No longer generated, kept as edited:", name)
+		return nil
+	}
+	fmt.Fprintf(LogOutput, "Edited in the chart, new version written to %s%s\n", name, ConflictSuffix)
+	return ioutil.WriteFile(filename+ConflictSuffix, generated, 0o644)
+}
+
+// readOptionalFile returns the content of a file, or nil if it does not exist.
+func readOptionalFile(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// saveGenerated copies the generated files of the chart in cdir to its
+// GeneratedDir, to be the base of the next update.
+func saveGenerated(cdir string) error {
+	baseDir := filepath.Join(cdir, GeneratedDir)
+	if err := os.RemoveAll(baseDir); err != nil {
+		return err
+	}
+	for _, name := range []string{ValuesfileName, SchemafileName, TemplatesDir, CRDsDir} {
+		src := filepath.Join(cdir, name)
+		fi, err := os.Stat(src)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			err = copyDir(src, filepath.Join(baseDir, name))
+		} else {
+			err = copyFile(src, filepath.Join(baseDir, name))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return ensureHelmignore(cdir)
+}
+
+// ensureHelmignore adds helmignorePatterns to the .helmignore of the chart.
+func ensureHelmignore(cdir string) error {
+	filename := filepath.Join(cdir, HelmignoreName)
+	data, err := readOptionalFile(filename)
+	if err != nil {
+		return err
+	}
+	present := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		present[strings.TrimSpace(scanner.Text())] = true
+	}
+	var missing []string
+	for _, p := range helmignorePatterns {
+		if !present[p] {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if len(data) != 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(data, "# Kept by chartify to update the chart\n"...)
+	data = append(data, strings.Join(missing, "\n")+"\n"...)
+	return ioutil.WriteFile(filename, data, 0o644)
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		return copyFile(path, filepath.Join(dst, rel))
+	})
+}
+
+func copyFile(src, dst string) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dst, data, 0o644)
+}
@@ -0,0 +1,178 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	ylib "github.com/ghodss/yaml"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// valueEnums are the allowed values of the values that hold an enum field of
+// the Kubernetes API.
+var valueEnums = map[string][]string{
+	ImagePullPolicy: {
+		string(apiv1.PullAlways),
+		string(apiv1.PullIfNotPresent),
+		string(apiv1.PullNever),
+	},
+	ServiceType: {
+		string(apiv1.ServiceTypeClusterIP),
+		string(apiv1.ServiceTypeNodePort),
+		string(apiv1.ServiceTypeLoadBalancer),
+		string(apiv1.ServiceTypeExternalName),
+	},
+	SessionAffinity: {
+		string(apiv1.ServiceAffinityNone),
+		string(apiv1.ServiceAffinityClientIP),
+	},
+	RestartPolicy: {
+		string(apiv1.RestartPolicyAlways),
+		string(apiv1.RestartPolicyOnFailure),
+		string(apiv1.RestartPolicyNever),
+	},
+	ReclaimPolicy: {
+		string(apiv1.PersistentVolumeReclaimRetain),
+		string(apiv1.PersistentVolumeReclaimDelete),
+		string(apiv1.PersistentVolumeReclaimRecycle),
+	},
+	AccessMode: {
+		string(apiv1.ReadWriteOnce),
+		string(apiv1.ReadOnlyMany),
+		string(apiv1.ReadWriteMany),
+		string(apiv1.ReadWriteOncePod),
+	},
+	ConcurrencyPolicy: {
+		string(batchv1.AllowConcurrent),
+		string(batchv1.ForbidConcurrent),
+		string(batchv1.ReplaceConcurrent),
+	},
+	DeploymentStrategy: {
+		string(appsv1.RecreateDeploymentStrategyType),
+		string(appsv1.RollingUpdateDeploymentStrategyType),
+	},
+}
+
+// freeFormValues are values copied verbatim from a Kubernetes API field,
+// whose content is left to the API server to validate.
+var freeFormValues = map[string]bool{
+	Resources:                 true,
+	SecurityContext:           true,
+	PodSecurityContext:        true,
+	NodeSelector:              true,
+	Affinity:                  true,
+	Tolerations:               true,
+	TopologySpreadConstraints: true,
+}
+
+// stringMapValues are values holding labels or annotations.
+var stringMapValues = map[string]bool{
+	Annotations:       true,
+	PodAnnotations:    true,
+	PodLabels:         true,
+	CommonLabels:      true,
+	CommonAnnotations: true,
+}
+
+// probeValues are the values of container probes. Only their enabled flag is
+// chartify's own, the rest is a Kubernetes probe.
+var probeValues = map[string]bool{
+	LivenessProbe:  true,
+	ReadinessProbe: true,
+	StartupProbe:   true,
+}
+
+// numericStringValues are string values that are often set as numbers, such
+// as an image tag written imageTag: 1.21 in a values file.
+var numericStringValues = map[string]bool{
+	ImageTag: true,
+}
+
+// requiredValues are the values that templates use without a default, so
+// they are required wherever the generated values set them.
+var requiredValues = map[string]bool{
+	Image:           true,
+	ImageTag:        true,
+	ImagePullPolicy: true,
+	Replicas:        true,
+	ServiceType:     true,
+}
+
+// valuesSchema returns the JSON schema of a values file, inferred from the
+// type of each default value.
+func valuesSchema(values []byte) ([]byte, error) {
+	data, err := ylib.YAMLToJSON(values)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v map[string]interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	schema := valueSchema("", v)
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// valueSchema returns the schema of the value v stored under key.
+func valueSchema(key string, v interface{}) map[string]interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		schema := map[string]interface{}{"type": "object"}
+		switch {
+		case freeFormValues[key]:
+			return schema
+		case stringMapValues[key]:
+			schema["additionalProperties"] = map[string]interface{}{"type": "string"}
+			return schema
+		case probeValues[key]:
+			v = map[string]interface{}{Enabled: v[Enabled]}
+		}
+		if len(v) == 0 {
+			return schema
+		}
+		properties := make(map[string]interface{}, len(v))
+		var required []string
+		for k, e := range v {
+			properties[k] = valueSchema(k, e)
+			if requiredValues[k] && e != nil {
+				required = append(required, k)
+			}
+		}
+		schema["properties"] = properties
+		if len(required) != 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+		return schema
+	case []interface{}:
+		return map[string]interface{}{"type": "array"}
+	case string:
+		schema := map[string]interface{}{"type": "string"}
+		if numericStringValues[key] {
+			schema["type"] = []string{"string", "number"}
+		}
+		if enum, ok := valueEnums[key]; ok && len(v) != 0 {
+			schema["enum"] = enum
+		}
+		return schema
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return map[string]interface{}{"type": "number"}
+		}
+		return map[string]interface{}{"type": "integer"}
+	case bool:
+		return map[string]interface{}{"type": "boolean"}
+	}
+	return map[string]interface{}{}
+}
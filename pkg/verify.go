@@ -0,0 +1,473 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/appscode/go/encoding/yaml"
+	ylib "github.com/ghodss/yaml"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// verifyRelease is the release the chart is rendered as by VerifyChart.
+var verifyRelease = chartutil.ReleaseOptions{Name: "verify", Namespace: "default"}
+
+// serverFields are the fields of an object that the API server manages and
+// chartify drops from templates.
+var serverFields = [][]string{
+	{"status"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "deletionTimestamp"},
+	{"metadata", "generateName"},
+	{"metadata", "generation"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "selfLink"},
+	{"metadata", "uid"},
+}
+
+// podSpecPaths are the paths of the pod specs of the kinds that chartify
+// cleans up with cleanUpPodSpec.
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// droppedPodSpecFields are the fields of pod specs that chartify leaves out
+// of templates, as they are filled in by the API server.
+var droppedPodSpecFields = []string{"dnsPolicy", "nodeName", "terminationGracePeriodSeconds"}
+
+// VerifyChart renders the chart in chartDir with its default values and
+// compares every rendered object with the object in objects it was generated
+// from. Fields added by the chart, such as its labels, are ignored, as are the
+// names chartify prefixes with the release, and selectors are checked to still
+// select the pods they did. It returns Mismatches listing the fields of
+// objects that were lost or changed.
+func VerifyChart(chartDir string, objects []string) error {
+	c, err := loader.Load(chartDir)
+	if err != nil {
+		return err
+	}
+	values, err := chartutil.ToRenderValues(c, nil, verifyRelease, nil)
+	if err != nil {
+		return err
+	}
+	files, err := engine.Render(c, values)
+	if err != nil {
+		return err
+	}
+	for _, crd := range c.CRDObjects() {
+		files[crd.Filename] = string(crd.File.Data)
+	}
+	var rendered []*unstructured.Unstructured
+	for name, content := range files {
+		if !strings.HasSuffix(name, ".yaml") {
+			continue
+		}
+		for _, doc := range releaseutil.SplitManifests(content) {
+			obj := &unstructured.Unstructured{}
+			if err := ylib.Unmarshal([]byte(doc), &obj.Object); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			if len(obj.Object) != 0 {
+				rendered = append(rendered, obj)
+			}
+		}
+	}
+
+	v := verifier{prefixes: []string{chartFullname(c.Name()) + "-", verifyRelease.Name + "-"}}
+	var sources, targets []*unstructured.Unstructured
+	for _, kubeObj := range objects {
+		kubeJson, err := yaml.ToJSON([]byte(kubeObj))
+		if err != nil {
+			return err
+		}
+		source := &unstructured.Unstructured{}
+		if err := json.Unmarshal(kubeJson, &source.Object); err != nil {
+			return err
+		}
+		cleanUpSource(source)
+		v.kind, v.name = source.GetKind(), source.GetName()
+		target := v.find(rendered, source)
+		if target == nil {
+			v.mismatches = append(v.mismatches, &Mismatch{Kind: v.kind, Name: v.name})
+			continue
+		}
+		sources, targets = append(sources, source.DeepCopy()), append(targets, target)
+		unstructured.RemoveNestedField(source.Object, "metadata", "name")
+		v.compare("", source.Object, target.Object)
+	}
+	v.compareSelectors(sources, targets)
+	if len(v.mismatches) != 0 {
+		return v.mismatches
+	}
+	return nil
+}
+
+// cleanUpSource removes the fields of an input object that chartify drops on
+// purpose.
+func cleanUpSource(obj *unstructured.Unstructured) {
+	for _, path := range serverFields {
+		unstructured.RemoveNestedField(obj.Object, path...)
+	}
+	switch obj.GetKind() {
+	case CRDKind:
+		cleanUpCRD(obj)
+	case "ServiceAccount":
+		secrets, _, _ := unstructured.NestedSlice(obj.Object, "secrets")
+		var kept []interface{}
+		for _, s := range secrets {
+			name, _, _ := unstructured.NestedString(s.(map[string]interface{}), "name")
+			// see serviceAccountTemplate
+			if !strings.HasPrefix(name, obj.GetName()+"-token-") {
+				kept = append(kept, s)
+			}
+		}
+		_ = unstructured.SetNestedSlice(obj.Object, kept, "secrets")
+	}
+	pruneSource(obj)
+}
+
+// pruneSource removes from obj the fields that chartify leaves out of
+// templates, as they are filled in by the API server or its controllers, and
+// the recommended labels, which the chart sets itself.
+func pruneSource(obj *unstructured.Unstructured) {
+	kind := obj.GetKind()
+	metadataPaths := [][]string{{"metadata"}}
+	selectorPaths := [][]string{{"spec", "selector", "matchLabels"}}
+	switch kind {
+	case "Service", "ReplicationController":
+		selectorPaths = [][]string{{"spec", "selector"}}
+	case "CronJob":
+		metadataPaths = append(metadataPaths, []string{"spec", "jobTemplate", "metadata"})
+		selectorPaths = append(selectorPaths, []string{"spec", "jobTemplate", "spec", "selector", "matchLabels"})
+	}
+	if kind == "Service" {
+		// see serviceTemplate
+		if ip, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); net.ParseIP(ip) != nil {
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+		}
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+	}
+	if podSpec, ok := podSpecPaths[kind]; ok {
+		prunePodSpec(obj.Object, podSpec)
+		if len(podSpec) > 1 {
+			metadataPaths = append(metadataPaths, fieldPath(podSpec[:len(podSpec)-1], "metadata"))
+		}
+	}
+	for _, path := range metadataPaths {
+		pruneLabels(obj.Object, fieldPath(path, "labels"))
+		pruneLabels(obj.Object, fieldPath(path, "annotations"))
+	}
+	for _, path := range selectorPaths {
+		pruneLabels(obj.Object, path)
+	}
+}
+
+// prunePodSpec removes the fields of the pod spec at path that
+// cleanUpPodSpec removes.
+func prunePodSpec(obj map[string]interface{}, path []string) {
+	spec, ok := nestedMap(obj, path...)
+	if !ok {
+		return
+	}
+	for _, f := range droppedPodSpecFields {
+		delete(spec, f)
+	}
+	if sa := spec["serviceAccount"]; sa == spec["serviceAccountName"] || sa == "default" {
+		delete(spec, "serviceAccount")
+	}
+	if spec["serviceAccountName"] == "default" {
+		delete(spec, "serviceAccountName")
+	}
+	for _, f := range []string{"containers", "initContainers"} {
+		containers, _ := spec[f].([]interface{})
+		for _, c := range containers {
+			if c, ok := c.(map[string]interface{}); ok {
+				delete(c, "terminationMessagePath")
+			}
+		}
+	}
+}
+
+// pruneLabels removes the decorators and the recommended labels from the
+// labels, annotations or selector at path.
+func pruneLabels(obj map[string]interface{}, path []string) {
+	labels, ok := nestedMap(obj, path...)
+	if !ok {
+		return
+	}
+	for k := range labels {
+		if isDecorator(k) || isRecommendedLabel(k) {
+			delete(labels, k)
+		}
+	}
+}
+
+// nestedMap returns the map at path in obj, without copying it.
+func nestedMap(obj map[string]interface{}, path ...string) (map[string]interface{}, bool) {
+	v, found, err := unstructured.NestedFieldNoCopy(obj, path...)
+	if err != nil || !found {
+		return nil, false
+	}
+	m, ok := v.(map[string]interface{})
+	return m, ok
+}
+
+// fieldPath returns path followed by fields, without modifying path.
+func fieldPath(path []string, fields ...string) []string {
+	return append(append([]string{}, path...), fields...)
+}
+
+// chartFullname returns the name the fullname helper template gives the
+// objects of the chart named name, with the default values.
+func chartFullname(name string) string {
+	fullname := verifyRelease.Name
+	if !strings.Contains(verifyRelease.Name, name) {
+		fullname = verifyRelease.Name + "-" + name
+	}
+	if len(fullname) > 63 {
+		fullname = fullname[:63]
+	}
+	return strings.TrimSuffix(fullname, "-")
+}
+
+type verifier struct {
+	// prefixes are prepended by the chart to the names of objects.
+	prefixes   []string
+	kind, name string
+	mismatches Mismatches
+}
+
+// find returns the rendered object generated from source.
+func (v *verifier) find(rendered []*unstructured.Unstructured, source *unstructured.Unstructured) *unstructured.Unstructured {
+	for _, obj := range rendered {
+		if obj.GetAPIVersion() == source.GetAPIVersion() && obj.GetKind() == source.GetKind() && v.sameString(source.GetName(), obj.GetName()) {
+			return obj
+		}
+	}
+	return nil
+}
+
+// sameString reports whether the rendered string is the source string, maybe
+// prefixed like the names of objects of the chart.
+func (v *verifier) sameString(source, rendered string) bool {
+	if source == rendered {
+		return true
+	}
+	for _, p := range v.prefixes {
+		if rendered == p+source {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *verifier) compare(path string, source, rendered interface{}) {
+	if rendered == nil && isOmittedValue(source) {
+		return
+	}
+	switch s := source.(type) {
+	case map[string]interface{}:
+		r, ok := rendered.(map[string]interface{})
+		if !ok {
+			v.report(path, source, rendered)
+			return
+		}
+		keys := make([]string, 0, len(s))
+		for k := range s {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			field := path + "." + k
+			if strings.ContainsAny(k, "./") {
+				field = fmt.Sprintf("%s[%q]", path, k)
+			}
+			if rv, found := r[k]; found {
+				v.compare(field, s[k], rv)
+			} else if !isOmittedValue(s[k]) {
+				v.report(field, s[k], nil)
+			}
+		}
+	case []interface{}:
+		r, ok := rendered.([]interface{})
+		if !ok {
+			v.report(path, source, rendered)
+			return
+		}
+		for i, e := range s {
+			field := fmt.Sprintf("%s[%d]", path, i)
+			if i < len(r) {
+				v.compare(field, e, r[i])
+			} else if !isOmittedValue(e) {
+				v.report(field, e, nil)
+			}
+		}
+	case string:
+		r, ok := rendered.(string)
+		if ok && strings.HasSuffix(path, "."+Image) && imageTag(s) == "latest" {
+			// untagged images get the latest tag, see addTemplateImageValue
+			s = strings.TrimSuffix(s, ":latest") + ":latest"
+		}
+		if !ok || (s != r && !(isPrefixedField(path) && v.sameString(s, r))) {
+			v.report(path, source, rendered)
+		}
+	default:
+		if !reflect.DeepEqual(source, rendered) {
+			v.report(path, source, rendered)
+		}
+	}
+}
+
+// compareSelectors reports the selectors among sources that select the pods
+// of an object among sources, but whose rendered selector, among targets, no
+// longer selects the rendered pods.
+func (v *verifier) compareSelectors(sources, targets []*unstructured.Unstructured) {
+	for i, source := range sources {
+		path := selectorPath(source.GetKind())
+		selector, _, _ := unstructured.NestedStringMap(source.Object, path...)
+		if len(selector) == 0 {
+			continue
+		}
+		renderedSelector, _, _ := unstructured.NestedStringMap(targets[i].Object, path...)
+		for j, pods := range sources {
+			if !selects(selector, podLabels(pods)) || selects(renderedSelector, podLabels(targets[j])) {
+				continue
+			}
+			v.kind, v.name = source.GetKind(), source.GetName()
+			v.report(strings.Join(path, "."), selector, renderedSelector)
+			break
+		}
+	}
+}
+
+func (v *verifier) report(field string, source, rendered interface{}) {
+	v.mismatches = append(v.mismatches, &Mismatch{
+		Kind:     v.kind,
+		Name:     v.name,
+		Field:    strings.TrimPrefix(field, "."),
+		Source:   source,
+		Rendered: rendered,
+	})
+}
+
+// nameFields are fields that hold the name of another object of the chart,
+// which chartify prefixes like the names of the objects themselves.
+var nameFields = map[string]bool{
+	"claimName":          true,
+	"secretName":         true,
+	"serviceAccountName": true,
+	"serviceName":        true,
+}
+
+// nameParents are fields whose name field holds the name of another object
+// of the chart.
+var nameParents = map[string]bool{
+	"configMap":        true,
+	"configMapKeyRef":  true,
+	"configMapRef":     true,
+	"imagePullSecrets": true,
+	"roleRef":          true,
+	"secretKeyRef":     true,
+	"secretRef":        true,
+	"secrets":          true,
+	"service":          true,
+	"subjects":         true,
+}
+
+// fieldIndex matches the list indexes and quoted keys in the paths reported
+// by verifier.compare.
+var fieldIndex = regexp.MustCompile(`\[[0-9]+\]|\[".*?"\]`)
+
+// isPrefixedField reports whether chartify may prefix the string at path with
+// the release: the names of objects of the chart.
+func isPrefixedField(path string) bool {
+	fields := strings.Split(strings.TrimPrefix(fieldIndex.ReplaceAllStringFunc(path, func(s string) string {
+		if strings.HasPrefix(s, `["`) {
+			return ".*"
+		}
+		return ""
+	}), "."), ".")
+	n := len(fields)
+	if n < 2 {
+		return false
+	}
+	last, parent := fields[n-1], fields[n-2]
+	return nameFields[last] || last == "name" && nameParents[parent]
+}
+
+// selectorPath returns the path of the label selector of objects of kind.
+func selectorPath(kind string) []string {
+	switch kind {
+	case "Service", "ReplicationController":
+		return []string{"spec", "selector"}
+	}
+	return []string{"spec", "selector", "matchLabels"}
+}
+
+// podLabels returns the labels of the pods of obj, or nil if it has none.
+func podLabels(obj *unstructured.Unstructured) map[string]string {
+	podSpec, ok := podSpecPaths[obj.GetKind()]
+	if !ok {
+		return nil
+	}
+	labels, _, _ := unstructured.NestedStringMap(obj.Object, fieldPath(podSpec[:len(podSpec)-1], "metadata", "labels")...)
+	return labels
+}
+
+// selects reports whether selector, which is not empty, selects labels.
+func selects(selector, labels map[string]string) bool {
+	if len(selector) == 0 || labels == nil {
+		return false
+	}
+	for k, v := range selector {
+		if l, ok := labels[k]; !ok || l != v {
+			return false
+		}
+	}
+	return true
+}
+
+func isRecommendedLabel(k string) bool {
+	for _, l := range recommendedLabels {
+		if k == l {
+			return true
+		}
+	}
+	return false
+}
+
+// isOmittedValue reports whether v is a value that chartify leaves out of
+// templates, as omitting it has the same meaning.
+func isOmittedValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		for _, e := range v {
+			if !isOmittedValue(e) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}